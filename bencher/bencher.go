@@ -6,14 +6,35 @@ package bencher
 
 import (
 	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
 	"io"
 	"log"
 	"mcs/pkg/mcs"
+	"os"
 	"time"
 )
 
+// checkpointPeriod is the time between two checkpoints of a running benchmark.
+const checkpointPeriod = 1 * time.Minute
+
 type Benchmark struct {
 	time.Duration
+	mcs.Budget
+	Seed int64
+
+	// Checkpoint, when set, names the file the tree is saved to during the run. An
+	// interrupted run resumes from its checkpoint, which is removed once the run
+	// completes. The time and playouts spent before the interruption are deducted
+	// from the duration and budget of the resumed run.
+	Checkpoint string
+
+	// Workers sizes the pipeline of concurrent searches.
+	Workers mcs.Workers
+
+	// VirtualLoss spreads the walkers of concurrent searches.
+	VirtualLoss mcs.VirtualLoss
 
 	name string
 	game *Game
@@ -41,30 +62,183 @@ func (b *Benchmark) Detach() *Game {
 }
 
 func (b *Benchmark) Run() error {
-	mcs.NodeCountReset()
-
 	game := b.game
 
 	initial := game.p.Initial()
-	ε, C, W := game.s.Ε(), game.s.C(), game.s.W()
+	conf := mcs.NewConfig(game.s.Epsilon(), game.s.C(), game.s.W())
+	if ucb := game.s.UCB(); ucb != nil {
+		conf.UCB = ucb
+	}
+	conf.Budget = b.Budget
+	conf.Seed = b.Seed
+	conf.Workers = b.Workers
+	conf.VirtualLoss = b.VirtualLoss
+	conf.Progress = func(p mcs.Progress) { // score-over-time curve
+		b.out.Println(" ", "progress", game.Name(), p.Elapsed, p.Playouts, p.Nodes, p.Decision.Score())
+	}
 
 	policies := game.s.Policies()
 
 	fun, duration := game.s.fun, b.Duration
 
+	root := mcs.NewRoot(initial, conf)
+
+	var before spent
+	if len(b.Checkpoint) > 0 {
+		loaded, run, err := loadTree(b.Checkpoint, initial)
+		switch {
+		case err == nil:
+			root, before = loaded, run
+		case !os.IsNotExist(err):
+			return err
+		}
+	}
+
+	// A resumed run is given what is left of its duration and budget.
+	over := false
+	if duration > 0 && before.Elapsed > 0 {
+		duration -= time.Duration(before.Elapsed)
+		over = duration <= 0
+	}
+	if conf.Budget.Playouts > 0 && before.Playouts > 0 {
+		conf.Budget.Playouts -= before.Playouts
+		over = over || conf.Budget.Playouts <= 0
+	}
+
+	if loaded := root.Config(); loaded != conf {
+		loaded.Budget, loaded.Seed, loaded.Progress = conf.Budget, conf.Seed, conf.Progress
+		loaded.Workers, loaded.VirtualLoss = conf.Workers, conf.VirtualLoss
+	}
+
 	var result mcs.Decision
+	var stats mcs.Stats
 	start := time.Now()
 	{
-		root := mcs.NewRoot(initial, ε, C, W)
-		result = fun(root, policies, duration)
+		// A zero duration leaves the budget as the only limit.
+		ctx, cancel := context.Background(), context.CancelFunc(func() {})
+		switch {
+		case over:
+			ctx, cancel = context.WithCancel(ctx)
+			cancel() // the search only concludes
+		case duration > 0:
+			ctx, cancel = context.WithTimeout(ctx, duration)
+		}
+
+		var err error
+
+		stop := b.checkpoints(root, before, start)
+		result, stats, err = fun(ctx, root, policies)
+		stop()
+
+		cancel()
+
+		if err != nil && !errors.Is(err, mcs.ErrNoMoves) {
+			return err
+		}
+
+		if len(b.Checkpoint) > 0 {
+			if err := os.Remove(b.Checkpoint); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
 	}
-	elapsed := time.Since(start)
+	elapsed := time.Duration(before.Elapsed) + time.Since(start)
 
-	b.out.Println(" ", mcs.Search(fun).String(), game.Name(), elapsed, mcs.NodeCount(), result)
+	b.out.Println(" ", mcs.Search(fun).String(), game.Name(), elapsed, stats.Nodes, result)
+	b.out.Println(" ", "stats", game.Name(), stats)
 
 	return nil
 }
 
+// spent is what a run has spent before being checkpointed, it's saved ahead of the
+// tree.
+type spent struct {
+	Elapsed  int64 // nanoseconds
+	Playouts int64
+}
+
+// checkpoints periodically saves the tree of a running search until stopped, with
+// what the run has spent since started, before included.
+func (b *Benchmark) checkpoints(root *mcs.Node, before spent, start time.Time) (stop func()) {
+	if len(b.Checkpoint) == 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(checkpointPeriod)
+		defer ticker.Stop()
+		defer close(stopped)
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				run := spent{
+					Elapsed:  before.Elapsed + int64(time.Since(start)),
+					Playouts: int64(root.Visits()), // every playout runs through the root
+				}
+				if err := saveTree(b.Checkpoint, root, run); err != nil {
+					b.out.Println(" ", "checkpoint", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// loadTree reads a tree saved from the initial position and what its run had spent.
+func loadTree(fname string, initial mcs.GameState) (*mcs.Node, spent, error) {
+	var run spent
+
+	file, err := os.Open(fname)
+	if err != nil {
+		return nil, run, err
+	}
+	defer file.Close()
+
+	if err := binary.Read(file, binary.LittleEndian, &run); err != nil {
+		return nil, run, err
+	}
+
+	root, err := mcs.LoadTree(file, initial)
+	return root, run, err
+}
+
+// saveTree writes a tree to a temporary file renamed once complete: a checkpoint
+// is never left half written.
+func saveTree(fname string, root *mcs.Node, run spent) error {
+	tmp := fname + ".tmp"
+
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	if err := binary.Write(file, binary.LittleEndian, run); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := mcs.SaveTree(file, root); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, fname)
+}
+
 type Game struct {
 	name string
 	p    *Problem
@@ -131,6 +305,8 @@ type Searcher struct {
 
 	policies []mcs.GamePolicy
 
+	ucb mcs.UCB
+
 	ε float64
 	c float64
 	w float64
@@ -144,7 +320,7 @@ func (s *Searcher) C() float64 {
 	return s.c
 }
 
-func (s *Searcher) Ε() float64 {
+func (s *Searcher) Epsilon() float64 {
 	return s.ε
 }
 
@@ -160,7 +336,7 @@ func (s *Searcher) SetC(c float64) {
 	s.c = c
 }
 
-func (s *Searcher) SetΕ(ε float64) {
+func (s *Searcher) SetEpsilon(ε float64) {
 	s.ε = ε
 }
 
@@ -172,10 +348,18 @@ func (s *Searcher) SetPolicies(policies []mcs.GamePolicy) {
 	s.policies = policies
 }
 
+func (s *Searcher) SetUCB(ucb mcs.UCB) {
+	s.ucb = ucb
+}
+
 func (s *Searcher) SetW(w float64) {
 	s.w = w
 }
 
+func (s *Searcher) UCB() mcs.UCB {
+	return s.ucb
+}
+
 func (s *Searcher) W() float64 {
 	return s.w
 }
@@ -8,9 +8,9 @@ package benchmark
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"log"
-	"math/rand"
 	"mcs/bencher"
 	"mcs/games/samegame"
 	"mcs/pkg/mcs"
@@ -27,10 +27,19 @@ import (
 const (
 	StandardSetPath = "../../assets/www.js-games.de/"
 	KB              = 1024
+
+	// Seed seeds the random generators of every search: runs differ by their
+	// configurations only.
+	Seed = 1
 )
 
+// virtualLoss adds the virtual loss schemes to the benchmark, it triples its length:
+// go test -timeout 0 -args -virtualloss
+var virtualLoss = flag.Bool("virtualloss", false, "compare virtual loss schemes with the exclusion of busy nodes")
+
 func TestSameGameStandardSet(t *testing.T) {
 
+	mcs.ServeMetrics()
 	go func() {
 		log.Println(http.ListenAndServe("localhost:6060", nil)) // 3...
 	}()
@@ -51,10 +60,16 @@ func TestSameGameStandardSet(t *testing.T) {
 		bencher.NewSearcher("Concurrent"),
 		//bencher.NewSearcher("Meta"),
 		//bencher.NewSearcher("Confident"),
+		//bencher.NewSearcher("Nested"),
+		//bencher.NewSearcher("RootParallel"),
+		//bencher.NewSearcher("LeafParallel"),
 	}
 	searchers[0].SetFun(mcs.ConcurrentSearch)
 	//searchers[1].SetFun(mcs.MetaSearch)
 	//searchers[2].SetFun(mcs.ConfidentSearch)
+	//searchers[3].SetFun(mcs.NestedSearch)
+	//searchers[4].SetFun(mcs.RootParallelSearch)
+	//searchers[5].SetFun(mcs.LeafParallelSearch)
 
 	// 1...
 	policies := []mcs.GamePolicy{
@@ -68,6 +83,20 @@ func TestSameGameStandardSet(t *testing.T) {
 		//{0.03, 40, 0.2},
 	}
 
+	// Virtual loss schemes are compared with the default exclusion of busy nodes
+	// on demand, see virtualLoss.
+	losses := []struct {
+		name string
+		mcs.VirtualLoss
+	}{
+		{"", mcs.VirtualLoss{}},
+		{"VL3", mcs.VirtualLoss{Loss: 3}},
+		{"VLx0.1", mcs.VirtualLoss{Loss: 0.1, Scaled: true}},
+	}
+	if !*virtualLoss {
+		losses = losses[:1]
+	}
+
 	durations := []time.Duration{
 		40 * time.Minute,
 		//20 * time.Minute,
@@ -78,37 +107,42 @@ func TestSameGameStandardSet(t *testing.T) {
 	for _, searcher := range searchers {
 		searcher.SetPolicies(policies)
 		for _, set := range constants {
-			searcher.SetΕ(set.ε)
+			searcher.SetEpsilon(set.ε)
 			searcher.SetC(set.C)
 			searcher.SetW(set.W)
-			for _, duration := range durations {
-				logname := "StandardSet" + searcher.Name() + duration.String() + "C" + fmt.Sprint(set.C)
-				logfile, err := os.Create(logname + ".log")
-				if err != nil {
-					t.Fatal(err)
-				}
-				logger := bufio.NewWriterSize(logfile, 1*KB)
+			for _, loss := range losses {
+				for _, duration := range durations {
+					logname := "StandardSet" + searcher.Name() + duration.String() + "C" + fmt.Sprint(set.C) + loss.name
+					logfile, err := os.Create(logname + ".log")
+					if err != nil {
+						t.Fatal(err)
+					}
+					logger := bufio.NewWriterSize(logfile, 1*KB)
 
-				for _, problem := range problems {
+					for _, problem := range problems {
 
-					game := bencher.NewGame()
-					game.SetP(problem)
-					game.SetS(searcher)
+						game := bencher.NewGame()
+						game.SetP(problem)
+						game.SetS(searcher)
 
-					name := logname + "_" + problem.Name()
-					benchmark := bencher.NewBenchmark(name, duration, logger)
-					benchmark.Duration = duration
-					benchmark.Attach(game)
+						name := logname + "_" + problem.Name()
+						benchmark := bencher.NewBenchmark(name, duration, logger)
+						benchmark.Duration = duration
+						benchmark.Seed = Seed
+						benchmark.Checkpoint = name + ".mcst" // resumes an interrupted run
+						benchmark.VirtualLoss = loss.VirtualLoss
+						benchmark.Attach(game)
 
-					if err := benchmark.Run(); err != nil {
-						t.Fatal(err)
-					}
+						if err := benchmark.Run(); err != nil {
+							t.Fatal(err)
+						}
 
-					benchmark.Detach()
-					flusher(logger)
-					runtime.GC() // Force memory recuperation before next iteration
+						benchmark.Detach()
+						flusher(logger)
+						runtime.GC() // Force memory recuperation before next iteration
+					}
+					closer(logfile)
 				}
-				closer(logfile)
 			}
 		}
 	}
@@ -163,10 +197,10 @@ func load(name string) mcs.GameState {
 		board = append(board, readln(reader))
 	}
 
-	rand.Seed(time.Now().UnixNano())
-
 	b := samegame.NewSameBoard(h, w)
-	b.Load(board)
+	if err := b.Load(board); err != nil {
+		panic(err)
+	}
 
-	return mcs.GameState(samegame.State(b))
+	return samegame.GameState(b)
 }
@@ -6,13 +6,15 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
-	"math/rand"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
 	"time"
@@ -35,15 +37,30 @@ const (
 
 var (
 	input       = flag.String("f", "", "problem file")
-	duration    = flag.String("t", "", "timeout")
+	duration    = flag.String("t", "", "timeout, none by default when a budget is given")
+	perMove     = flag.String("m", "", "per-move time, the game is played move by move when set")
 	interactive = flag.Bool("i", false, "launch an inspection console at the end of the search")
-	profiling   = flag.Bool("pprof", false, "launch a live profiling web service on port 6060")
+	profiling   = flag.Bool("pprof", false, "launch a live profiling and metrics web service on port 6060")
+	verbose     = flag.Bool("v", false, "log every improving decision found during the search")
+	playouts    = flag.Int64("n", 0, "playouts budget, none by default")
+	nodes       = flag.Int("nodes", 0, "created nodes budget, none by default")
+	maxNodes    = flag.Int("maxnodes", 0, "tree size cap, low-visit subtrees are pruned once reached, none by default")
+	seed        = flag.Int64("s", 0, "random seed, a seed and a budget reproduce a search")
+	export      = flag.String("export", "", "export the tree to a file, as JSON for a .json file, as DOT otherwise")
+	depth       = flag.Int("depth", 3, "depth of the exported tree, 0 for no limit")
+	save        = flag.String("save", "", "save the tree to a file at the end of the search")
+	resume      = flag.String("load", "", "resume the search from a saved tree, its constants are kept")
+	workers     = flag.String("workers", "", "walkers,samplers,updaters goroutines, defaults depend on the number of CPUs")
+	adaptive    = flag.Bool("adaptive", false, "move goroutines between stages of the pipeline while searching")
+	loss        = flag.Float64("vl", 0, "virtual loss per simulation in progress, none by default")
+	scaled      = flag.Bool("vlscaled", false, "scale the virtual loss by the visits of nodes")
 )
 
 func main() {
 	flag.Parse()
 
 	if *profiling {
+		mcs.ServeMetrics()
 		go func() {
 			log.Println(http.ListenAndServe("localhost:6060", nil))
 		}()
@@ -58,39 +75,98 @@ func main() {
 
 	writer := bufio.NewWriterSize(os.Stdout, 1*KB)
 
-	rand.Seed(time.Now().UnixNano())
-
+	// A budget bounds the search instead of the clock: results then don't depend
+	// on the speed of the machine, unless a timeout is also given.
 	var timeout = defaultTimeout
 	if len(*duration) > 0 {
 		duration, err := time.ParseDuration(*duration)
 		if err == nil {
 			timeout = duration
 		}
+	} else if *playouts > 0 || *nodes > 0 {
+		timeout = 0
 	}
 
 	h, w, board := load(input)
 	b := samegame.NewSameBoard(h, w)
-	b.Load(board)
+	if err := b.Load(board); err != nil {
+		panic(err)
+	}
 
 	writeln(writer, b.String())
 	flush(writer)
 
 	{ // Init has been taken care of, this is the core code:
-		gs := mcs.GameState(b)
+		gs := samegame.GameState(b)
 
 		policies := []mcs.GamePolicy{
 			mcs.GamePolicy(samegame.TabooColor),
 		}
 
-		root := mcs.NewRoot(gs, ε, C, W)
+		conf := mcs.NewConfig(ε, C, W)
+		conf.Budget = mcs.Budget{Playouts: *playouts, Nodes: *nodes}
+		conf.Seed = *seed
+		conf.MaxNodes = *maxNodes
+		conf.Workers = parseWorkers(*workers)
+		conf.Workers.Adaptive = *adaptive
+		conf.VirtualLoss = mcs.VirtualLoss{Loss: *loss, Scaled: *scaled}
+		if *verbose {
+			conf.Progress = func(p mcs.Progress) {
+				log.Printf("[progress] %v\n", p)
+			}
+		}
+
+		root := mcs.NewRoot(gs, conf)
+		if len(*resume) > 0 {
+			root = loadTree(*resume, gs)
+
+			loaded := root.Config()
+			loaded.Budget, loaded.Seed, loaded.Progress = conf.Budget, conf.Seed, conf.Progress
+			loaded.Workers, loaded.VirtualLoss = conf.Workers, conf.VirtualLoss
+			loaded.MaxNodes = conf.MaxNodes
+		}
+
+		// The search stops on budget, timeout or interrupt (^C): the best
+		// decision found so far is printed anyway.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		cancel := context.CancelFunc(func() {})
+		if timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+		}
+
+		// Moves are committed one at a time with a per-move clock, the
+		// budget if any is then spent on each move.
+		search, online := mcs.Search(mcs.ConcurrentSearch), false
+		if len(*perMove) > 0 {
+			if duration, err := time.ParseDuration(*perMove); err == nil {
+				search, online = mcs.OnlineSearch(search, duration), true
+			}
+		}
 
 		start := time.Now()
-		result := mcs.ConcurrentSearch(root, policies, timeout)
+		result, stats, err := search(ctx, root, policies)
 		elapsed := time.Since(start)
 
+		cancel()
+		stop()
+
+		// A finished board is no error: its final score is the result.
+		if err != nil && !errors.Is(err, mcs.ErrNoMoves) {
+			panic(err)
+		}
+
+		// The tree is dismantled move after move when playing online.
+		if len(*save) > 0 && !online {
+			saveTree(*save, root)
+		}
+
+		if len(*export) > 0 && !online {
+			exportTree(*export, root, mcs.Cut{Depth: *depth})
+		}
+
 		replay(writer, b, result)
 
-		writeln(writer, fmt.Sprintf("CMCT took %v value: %v (%d nodes)", elapsed, result.Score(), mcs.NodeCount()))
+		writeln(writer, fmt.Sprintf("CMCT took %v value: %v solved: %v (%v)", elapsed, result.Score(), result.Solved(), stats))
 		flush(writer)
 
 		if *interactive {
@@ -124,17 +200,84 @@ func load(fname *string) (h, w int, board []string) {
 	return
 }
 
+func loadTree(fname string, initial mcs.GameState) *mcs.Node {
+	file, err := os.Open(fname)
+	if err != nil {
+		panic(err)
+	}
+
+	root, err := mcs.LoadTree(file, initial)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := file.Close(); err != nil {
+		panic(err)
+	}
+
+	return root
+}
+
+func saveTree(fname string, root *mcs.Node) {
+	file, err := os.Create(fname)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := mcs.SaveTree(file, root); err != nil {
+		panic(err)
+	}
+
+	if err := file.Close(); err != nil {
+		panic(err)
+	}
+}
+
+func exportTree(fname string, root *mcs.Node, cut mcs.Cut) {
+	file, err := os.Create(fname)
+	if err != nil {
+		panic(err)
+	}
+
+	write := mcs.WriteDOT
+	if strings.HasSuffix(fname, ".json") {
+		write = mcs.WriteJSON
+	}
+
+	if err := write(file, root, cut); err != nil {
+		panic(err)
+	}
+
+	if err := file.Close(); err != nil {
+		panic(err)
+	}
+}
+
 func replay(writer *bufio.Writer, b samegame.SameBoard, solution mcs.Decision) {
 	moves := solution.Moves()
 	for i, tile := range moves {
-		color := b.TileColor(chaingame.Tile(tile))
-		b = b.Remove(chaingame.Tile(tile))
+		color := b.TileColor(chaingame.Tile(tile.(samegame.Move)))
+		b = b.Remove(chaingame.Tile(tile.(samegame.Move)))
 
 		writeln(writer, fmt.Sprintf("\n#%d Removed: %s", i+1, color.AnsiColoredString(tile.String())))
 		writeln(writer, b.String())
 	}
 }
 
+// parseWorkers reads walkers,samplers,updaters counts, missing ones are defaults.
+func parseWorkers(s string) mcs.Workers {
+	var counts [3]int
+	if len(s) > 0 {
+		for i, field := range strings.SplitN(s, ",", len(counts)) {
+			if len(field) > 0 {
+				counts[i] = atoi(field)
+			}
+		}
+	}
+
+	return mcs.Workers{Walkers: counts[0], Samplers: counts[1], Updaters: counts[2]}
+}
+
 func atoi(s string) int {
 	n, err := strconv.Atoi(s)
 	if err != nil {
@@ -6,13 +6,15 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
-	"math/rand"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
 	"time"
@@ -35,15 +37,20 @@ const (
 
 var (
 	input       = flag.String("f", "", "problem file")
-	duration    = flag.String("t", "", "timeout")
+	duration    = flag.String("t", "", "timeout, none by default when a budget is given")
 	interactive = flag.Bool("i", false, "launch an inspection console at the end of the search")
-	profiling   = flag.Bool("pprof", false, "launch a live profiling web service on port 6060")
+	profiling   = flag.Bool("pprof", false, "launch a live profiling and metrics web service on port 6060")
+	verbose     = flag.Bool("v", false, "log every improving decision found during the search")
+	playouts    = flag.Int64("n", 0, "playouts budget, none by default")
+	nodes       = flag.Int("nodes", 0, "created nodes budget, none by default")
+	seed        = flag.Int64("s", 0, "random seed, a seed and a budget reproduce a search")
 )
 
 func main() {
 	flag.Parse()
 
 	if *profiling {
+		mcs.ServeMetrics()
 		go func() {
 			log.Println(http.ListenAndServe("localhost:6060", nil))
 		}()
@@ -58,39 +65,68 @@ func main() {
 
 	writer := bufio.NewWriterSize(os.Stdout, 1*KB)
 
-	rand.Seed(time.Now().UnixNano())
-
+	// A budget bounds the search instead of the clock: results then don't depend
+	// on the speed of the machine, unless a timeout is also given.
 	var timeout = defaultTimeout
 	if len(*duration) > 0 {
 		duration, err := time.ParseDuration(*duration)
-		if err != nil {
+		if err == nil {
 			timeout = duration
 		}
+	} else if *playouts > 0 || *nodes > 0 {
+		timeout = 0
 	}
 
 	h, w, board := load(input)
 	b := samegame.NewSameBoard(h, w)
-	b.Load(board)
+	if err := b.Load(board); err != nil {
+		panic(err)
+	}
 
 	writeln(writer, b.String())
 	flush(writer)
 
 	{ // Everything has been taken care of, this is the core code:
-		gs := mcs.GameState(b)
+		gs := samegame.GameState(b)
 
 		policies := []mcs.GamePolicy{
 			mcs.GamePolicy(samegame.TabooColor),
 		}
 
-		root := mcs.NewRoot(gs, ε, C, W)
+		conf := mcs.NewConfig(ε, C, W)
+		conf.Budget = mcs.Budget{Playouts: *playouts, Nodes: *nodes}
+		conf.Seed = *seed
+		if *verbose {
+			conf.Progress = func(p mcs.Progress) {
+				log.Printf("[progress] %v\n", p)
+			}
+		}
+
+		root := mcs.NewRoot(gs, conf)
+
+		// The search stops on budget, timeout or interrupt (^C): the best
+		// decision found so far is printed anyway.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		cancel := context.CancelFunc(func() {})
+		if timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+		}
 
 		start := time.Now()
-		result := mcs.MetaSearch(root, policies, timeout)
+		result, stats, err := mcs.MetaSearch(ctx, root, policies)
 		elapsed := time.Since(start)
 
+		cancel()
+		stop()
+
+		// A finished board is no error: its final score is the result.
+		if err != nil && !errors.Is(err, mcs.ErrNoMoves) {
+			panic(err)
+		}
+
 		replay(writer, b, result)
 
-		writeln(writer, fmt.Sprintf("Meta took %v value: %v (%d nodes)", elapsed, result.Score(), mcs.NodeCount()))
+		writeln(writer, fmt.Sprintf("Meta took %v value: %v solved: %v (%v)", elapsed, result.Score(), result.Solved(), stats))
 		flush(writer)
 
 		if *interactive {
@@ -127,8 +163,8 @@ func load(fname *string) (h, w int, board []string) {
 func replay(writer *bufio.Writer, b samegame.SameBoard, solution mcs.Decision) {
 	moves := solution.Moves()
 	for i, tile := range moves {
-		color := b.TileColor(chaingame.Tile(tile))
-		b = b.Remove(chaingame.Tile(tile))
+		color := b.TileColor(chaingame.Tile(tile.(samegame.Move)))
+		b = b.Remove(chaingame.Tile(tile.(samegame.Move)))
 
 		writeln(writer, fmt.Sprintf("\n#%d Removed: %s", i+1, color.AnsiColoredString(tile.String())))
 		writeln(writer, b.String())
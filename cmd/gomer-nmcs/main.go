@@ -0,0 +1,204 @@
+// Copyright 2018 Erik Adelbert. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	_ "net/http/pprof"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"mcs/games/samegame"
+	"mcs/pkg/chaingame"
+	"mcs/pkg/mcs"
+)
+
+const (
+	KB = 1024
+
+	defaultTimeout = 1 * time.Minute // This program has in +/- 10ms accuracy due to its structure.
+
+	ε = 0.03 // ε-greedy
+
+	C = 40  // UCB-SP
+	W = 0.0 // UCB Best score
+)
+
+var (
+	input       = flag.String("f", "", "problem file")
+	duration    = flag.String("t", "", "timeout, none by default when a budget is given")
+	interactive = flag.Bool("i", false, "launch an inspection console at the end of the search")
+	profiling   = flag.Bool("pprof", false, "launch a live profiling and metrics web service on port 6060")
+	verbose     = flag.Bool("v", false, "log every improving decision found during the search")
+	playouts    = flag.Int64("n", 0, "playouts budget, none by default")
+	nodes       = flag.Int("nodes", 0, "created nodes budget, none by default")
+	seed        = flag.Int64("s", 0, "random seed, a seed and a budget reproduce a search")
+	level       = flag.Int("l", mcs.DefaultLevel, "nesting level")
+)
+
+func main() {
+	flag.Parse()
+
+	if *profiling {
+		mcs.ServeMetrics()
+		go func() {
+			log.Println(http.ListenAndServe("localhost:6060", nil))
+		}()
+	}
+
+	if len(*input) == 0 {
+		if err := fmt.Errorf("no input file"); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	writer := bufio.NewWriterSize(os.Stdout, 1*KB)
+
+	// A budget bounds the search instead of the clock: results then don't depend
+	// on the speed of the machine, unless a timeout is also given.
+	var timeout = defaultTimeout
+	if len(*duration) > 0 {
+		duration, err := time.ParseDuration(*duration)
+		if err == nil {
+			timeout = duration
+		}
+	} else if *playouts > 0 || *nodes > 0 {
+		timeout = 0
+	}
+
+	h, w, board := load(input)
+	b := samegame.NewSameBoard(h, w)
+	if err := b.Load(board); err != nil {
+		panic(err)
+	}
+
+	writeln(writer, b.String())
+	flush(writer)
+
+	{ // Everything has been taken care of, this is the core code:
+		gs := samegame.GameState(b)
+
+		policies := []mcs.GamePolicy{
+			mcs.GamePolicy(samegame.TabooColor),
+		}
+
+		conf := mcs.NewConfig(ε, C, W)
+		conf.Budget = mcs.Budget{Playouts: *playouts, Nodes: *nodes}
+		conf.Seed = *seed
+		if *verbose {
+			conf.Progress = func(p mcs.Progress) {
+				log.Printf("[progress] %v\n", p)
+			}
+		}
+
+		root := mcs.NewRoot(gs, conf)
+
+		// The search stops on budget, timeout or interrupt (^C): the best
+		// decision found so far is printed anyway.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		cancel := context.CancelFunc(func() {})
+		if timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+		}
+
+		start := time.Now()
+		result, stats, err := mcs.NestedSearchLevel(*level)(ctx, root, policies)
+		elapsed := time.Since(start)
+
+		cancel()
+		stop()
+
+		// A finished board is no error: its final score is the result.
+		if err != nil && !errors.Is(err, mcs.ErrNoMoves) {
+			panic(err)
+		}
+
+		replay(writer, b, result)
+
+		writeln(writer, fmt.Sprintf("NMCS took %v value: %v solved: %v (%v)", elapsed, result.Score(), result.Solved(), stats))
+		flush(writer)
+
+		if *interactive {
+			mcs.Cli(root)
+		}
+	}
+
+	//panic("Show stack")
+}
+
+func load(fname *string) (h, w int, board []string) {
+	file, err := os.Open(*fname)
+	if err != nil {
+		panic(err)
+	}
+
+	reader := bufio.NewReaderSize(file, 1*KB)
+
+	fields := strings.Split(readln(reader), " ")
+	h, w = atoi(fields[0]), atoi(fields[1])
+
+	board = make([]string, 0, h)
+	for i := 0; i < h; i++ {
+		board = append(board, readln(reader))
+	}
+
+	if err := file.Close(); err != nil {
+		panic(err)
+	}
+
+	return
+}
+
+func replay(writer *bufio.Writer, b samegame.SameBoard, solution mcs.Decision) {
+	moves := solution.Moves()
+	for i, tile := range moves {
+		color := b.TileColor(chaingame.Tile(tile.(samegame.Move)))
+		b = b.Remove(chaingame.Tile(tile.(samegame.Move)))
+
+		writeln(writer, fmt.Sprintf("\n#%d Removed: %s", i+1, color.AnsiColoredString(tile.String())))
+		writeln(writer, b.String())
+	}
+}
+
+func atoi(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+func readln(reader *bufio.Reader) string {
+	s, _, err := reader.ReadLine()
+	if err != nil {
+		panic(err)
+	}
+	return string(s)
+}
+
+func writeln(w *bufio.Writer, s string) {
+	if _, err := w.WriteString(s); err != nil {
+		panic(err)
+	}
+	if err := w.WriteByte('\n'); err != nil {
+		panic(err)
+	}
+}
+
+func flush(w *bufio.Writer) {
+	if err := w.Flush(); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,35 @@
+package clickgame
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"mcs/pkg/mcs"
+)
+
+func TestGameState_Sample(t *testing.T) {
+	b := NewClickBoard(4, 4)
+	b.Load([]string{
+		"VBVB",
+		"BVVV",
+		"VBVB",
+		"BVVB",
+	})
+	g := GameState(b)
+
+	score, moves := g.Clone().Sample(context.Background(), rand.New(rand.NewSource(1)), NoTaboo)
+
+	replay := mcs.GameState(g.Clone())
+	for _, move := range moves {
+		replay = replay.Play(move)
+	}
+
+	if s := replay.Score(); s != score {
+		t.Errorf("sample: replayed %g, expected %g", s, score)
+	}
+
+	if replay.Moves().Len() != 0 {
+		t.Errorf("sample: game is not over")
+	}
+}
@@ -5,6 +5,7 @@
 package clickgame
 
 import (
+	"math/rand"
 	"strings"
 
 	"mcs/pkg/chaingame"
@@ -39,13 +40,17 @@ func (cb ClickBoard) Clone() ClickBoard {
 	return ClickBoard{board, histo}
 }
 
-// Load a ClickBoard from strings and initializes its histogram.
-func (cb ClickBoard) Load(buf []string) {
-	cb.Board.Load(buf)
+// Load a ClickBoard from strings and initializes its histogram, see chaingame.Board.Load.
+func (cb ClickBoard) Load(buf []string) error {
+	if err := cb.Board.Load(buf); err != nil {
+		return err
+	}
 
 	for k, v := range cb.Board.Histogram() {
 		cb.Histogram[k] = v
 	}
+
+	return nil
 }
 
 // Randomize the ClickBoard  with n colors :
@@ -53,9 +58,9 @@ func (cb ClickBoard) Load(buf []string) {
 //  - passing 'AllColors' adds one of every color to the list.
 //  - a one color list produces a board filled with a unique tile.
 //
-// c.Randomize(AllColors, Red, Indigo)
-func (cb ClickBoard) Randomize(list ...chaingame.Color) {
-	cb.Board.Randomize(list...)
+// c.Randomize(rng, AllColors, Red, Indigo)
+func (cb ClickBoard) Randomize(rng *rand.Rand, list ...chaingame.Color) {
+	cb.Board.Randomize(rng, list...)
 
 	for k, v := range cb.Board.Histogram() {
 		cb.Histogram[k] = v
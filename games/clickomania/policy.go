@@ -22,6 +22,14 @@ const (
 // ColorPolicy is used for selecting nodes and moves during playouts.
 type ColorPolicy func(ClickBoard) (chaingame.Color, Mode)
 
+// WeightedPolicy asks for moves to be chosen with a probability proportional to
+// their weight, eg. chaingame.TileSize, instead of uniformly. A nil Taboo stands
+// for NoTaboo.
+type WeightedPolicy struct {
+	Taboo  ColorPolicy
+	Weight chaingame.Weighting
+}
+
 // NoTaboo deactivate taboo selection.
 func NoTaboo(board ClickBoard) (chaingame.Color, Mode) {
 	_ = board
@@ -32,8 +40,8 @@ func NoTaboo(board ClickBoard) (chaingame.Color, Mode) {
 func TabooColor(board ClickBoard) (chaingame.Color, Mode) {
 
 	taboo, max := chaingame.NoColor, 0.0
-	for c, n := range board.Histogram() {
-		if n > max {
+	for c := chaingame.Red; c < chaingame.AllColors; c++ { // not in map order
+		if n := board.Histogram[c]; n > max {
 			taboo, max = c, n
 		}
 	}
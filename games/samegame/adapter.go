@@ -0,0 +1,156 @@
+// Copyright 2018 Erik Adelbert. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file adapts samegame to the mcs game contract.
+
+package samegame
+
+import (
+	"context"
+	"math/rand"
+
+	"mcs/pkg/chaingame"
+	"mcs/pkg/mcs"
+)
+
+// GameState is a State seen from mcs.
+type GameState State
+
+var (
+	_ mcs.GameState = GameState{}
+	_ mcs.Coder     = GameState{}
+	_ mcs.Hasher    = GameState{}
+)
+
+// Clone returns a memory-independent copy.
+func (g GameState) Clone() mcs.GameState {
+	return GameState(State(g).Clone())
+}
+
+// Code encodes a move for mcs learned policies.
+func (g GameState) Code(m mcs.Move) uint64 {
+	return State(g).Code(m.(Move), DefaultCoding)
+}
+
+// Hash identifies the position for mcs transposition tables.
+func (g GameState) Hash() uint64 {
+	return SameBoard(g).Board.Hash()
+}
+
+// Moves returns the legal moves.
+func (g GameState) Moves() mcs.MoveSet {
+	return MoveSet(State(g).Moves())
+}
+
+// Play returns the state following a ply.
+func (g GameState) Play(m mcs.Move) mcs.GameState {
+	return GameState(State(g).Play(m.(Move)))
+}
+
+// Sample simulates a game to its end by applying either a ColorPolicy, a
+// WeightedPolicy or mcs.Weights learned over moves codes.
+func (g GameState) Sample(ctx context.Context, rng *rand.Rand, policy mcs.GamePolicy) (float64, mcs.MoveSequence) {
+	var score float64
+	var seq Sequence
+
+	switch p := policy.(type) {
+	case mcs.Weights:
+		return g.sampleWeighted(ctx, rng, p)
+	case WeightedPolicy:
+		score, seq = State(g).SampleBy(ctx, rng, colorPolicy(p.Taboo), p.Weight)
+	default:
+		score, seq = State(g).Sample(ctx, rng, colorPolicy(policy))
+	}
+
+	moves := make(mcs.MoveSequence, 0, seq.Len())
+	for _, move := range seq {
+		moves = append(moves, move)
+	}
+
+	return score, moves
+}
+
+// sampleWeighted simulates a game to its end, moves are chosen according to
+// their weights.
+func (g GameState) sampleWeighted(ctx context.Context, rng *rand.Rand, weights mcs.Weights) (float64, mcs.MoveSequence) {
+	state := State(g)
+
+	var moves mcs.MoveSequence
+	var score float64
+
+	done := ctx.Done()
+	for hand := state.Moves().List(); len(hand) > 0; hand = state.Moves().List() {
+		select {
+		case <-done:
+			return score, moves
+		default:
+			codes := make([]uint64, len(hand))
+			for i, move := range hand {
+				codes[i] = state.Code(move, DefaultCoding)
+			}
+			move := hand[weights.Pick(rng, codes)]
+
+			state = state.Play(move)
+
+			moves = moves.Enqueue(move)
+			score += move.Score()
+		}
+	}
+	score += state.Score()
+
+	return score, moves
+}
+
+// Score returns a statically computed score of the calling state.
+func (g GameState) Score() float64 {
+	return State(g).Score()
+}
+
+func (g GameState) String() string {
+	return State(g).String()
+}
+
+// MoveSet is a Hand seen from mcs.
+type MoveSet Hand
+
+var _ mcs.MoveSet = MoveSet{}
+
+// Draw randomly removes a move from the set.
+func (m MoveSet) Draw(rng *rand.Rand) (mcs.Move, mcs.MoveSet) {
+	move, hand := Hand(m).Draw(rng)
+	return move, MoveSet(hand)
+}
+
+// Len returns the number of legal moves.
+func (m MoveSet) Len() int {
+	return Hand(m).Len()
+}
+
+// List returns all the moves present in the set.
+func (m MoveSet) List() []mcs.Move {
+	list := Hand(m).List()
+
+	moves := make([]mcs.Move, 0, len(list))
+	for _, move := range list {
+		moves = append(moves, move)
+	}
+	return moves
+}
+
+// colorPolicy recovers a ColorPolicy from a mcs.GamePolicy. Plain functions
+// are accepted as well as nil which stands for NoTaboo. Any other policy is
+// ignored: NoTaboo is used instead.
+func colorPolicy(policy mcs.GamePolicy) ColorPolicy {
+	switch p := policy.(type) {
+	case ColorPolicy:
+		if p == nil {
+			return NoTaboo
+		}
+		return p
+	case func(SameBoard) (chaingame.Color, Mode):
+		return p
+	default:
+		return NoTaboo
+	}
+}
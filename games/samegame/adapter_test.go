@@ -0,0 +1,213 @@
+package samegame
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"mcs/pkg/chaingame"
+	"mcs/pkg/mcs"
+)
+
+func newTestState() GameState {
+	b := NewSameBoard(4, 4)
+	b.Load([]string{
+		"VBVB",
+		"BVVV",
+		"VBVB",
+		"BVVB",
+	})
+	return GameState(b)
+}
+
+func TestGameState_Moves(t *testing.T) {
+	g := newTestState()
+
+	if n := g.Moves().Len(); n != 2 {
+		t.Errorf("moves: expected 2, got %d", n)
+	}
+}
+
+func TestGameState_Sample(t *testing.T) {
+	g := newTestState()
+
+	score, moves := g.Clone().Sample(context.Background(), rand.New(rand.NewSource(1)), TabooColor)
+
+	replay := mcs.GameState(g.Clone())
+	total := 0.0
+	for _, move := range moves {
+		total += move.Score()
+		replay = replay.Play(move)
+	}
+	total += replay.Score()
+
+	if total != score {
+		t.Errorf("sample: replayed %g, expected %g", total, score)
+	}
+
+	if replay.Moves().Len() != 0 {
+		t.Errorf("sample: game is not over")
+	}
+}
+
+func TestGameState_Sample_cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, moves := newTestState().Sample(ctx, rand.New(rand.NewSource(1)), TabooColor); moves.Len() != 0 {
+		t.Errorf("sample: %d moves played after cancellation", moves.Len())
+	}
+}
+
+func TestConfidentSearch(t *testing.T) {
+	g := newTestState()
+
+	root := mcs.NewRoot(g.Clone(), mcs.NewConfig(0.03, 40, 0))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+
+	replay := mcs.GameState(g.Clone())
+	result, _, _ := mcs.ConfidentSearch(ctx, root, []mcs.GamePolicy{NoTaboo})
+	cancel()
+	total := 0.0
+	for _, move := range result.Moves() {
+		total += move.Score()
+		replay = replay.Play(move)
+	}
+	total += replay.Score()
+
+	if total != result.Score() {
+		t.Errorf("search: replayed %g, expected %g", total, result.Score())
+	}
+}
+
+func TestConfidentSearch_reproducible(t *testing.T) {
+	b := NewSameBoard(8, 8)
+	b.Randomize(rand.New(rand.NewSource(1)), chaingame.Red, chaingame.Green, chaingame.Blue)
+
+	search := func() mcs.Decision {
+		conf := mcs.NewConfig(0.03, 40, 0)
+		conf.Seed = 7
+		conf.Budget = mcs.Budget{Playouts: 200}
+
+		root := mcs.NewRoot(GameState(b.Clone()), conf)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		{
+			result, _, _ := mcs.ConfidentSearch(ctx, root, []mcs.GamePolicy{TabooColor})
+			return result
+		}
+	}
+
+	if first, second := search(), search(); first.String() != second.String() {
+		t.Errorf("search: same seed, %v then %v", first, second)
+	}
+}
+
+func TestSaveTree(t *testing.T) {
+	b := NewSameBoard(8, 8)
+	b.Randomize(rand.New(rand.NewSource(1)), chaingame.Red, chaingame.Green, chaingame.Blue)
+
+	conf := mcs.NewConfig(0.03, 40, 0)
+	conf.Seed = 7
+	conf.Budget = mcs.Budget{Playouts: 200}
+
+	root := mcs.NewRoot(GameState(b.Clone()), conf)
+	mcs.ConfidentSearch(context.Background(), root, []mcs.GamePolicy{TabooColor})
+
+	var saved, resaved bytes.Buffer
+	if err := mcs.SaveTree(&saved, root); err != nil {
+		t.Fatal(err)
+	}
+	file := saved.Bytes()
+
+	// Positions are rebuilt from the board by replaying moves.
+	loaded, err := mcs.LoadTree(bytes.NewReader(file), GameState(b.Clone()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mcs.SaveTree(&resaved, loaded); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(file, resaved.Bytes()) {
+		t.Errorf("save: loaded tree differs")
+	}
+
+	if loaded.Best().String() != root.Best().String() {
+		t.Errorf("save: best decision %v loaded as %v", root.Best(), loaded.Best())
+	}
+}
+
+func TestConfidentSearch_solved(t *testing.T) {
+	g := newTestState()
+
+	root := mcs.NewRoot(g.Clone(), mcs.NewConfig(0.03, 40, 0))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	result, _, _ := mcs.ConfidentSearch(ctx, root, []mcs.GamePolicy{NoTaboo})
+	cancel()
+
+	if !result.Solved() {
+		t.Errorf("search: small board not solved")
+	}
+}
+
+func TestConfidentSearch_terminal(t *testing.T) {
+	b := NewSameBoard(4, 4)
+	b.Load([]string{
+		"VBVB",
+		"BVBV",
+		"VBVB",
+		"BVBV",
+	})
+
+	root := mcs.NewRoot(GameState(b), mcs.NewConfig(0.03, 40, 0))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	result, _, err := mcs.ConfidentSearch(ctx, root, []mcs.GamePolicy{NoTaboo})
+	cancel()
+
+	if !errors.Is(err, mcs.ErrNoMoves) {
+		t.Errorf("search: expected %v, got %v", mcs.ErrNoMoves, err)
+	}
+
+	if !result.Solved() || result.Score() != -128 {
+		t.Errorf("search: expected solved -128, got %v", result)
+	}
+}
+
+// TestConcurrentSearch runs CMCT on small boards, it is meant to be run with the
+// race detector, eg. make race.
+func TestConcurrentSearch(t *testing.T) {
+	random := NewSameBoard(6, 6)
+	random.Randomize(rand.New(rand.NewSource(1)), chaingame.Red, chaingame.Green, chaingame.Blue)
+
+	for _, g := range []GameState{newTestState(), GameState(random)} {
+		conf := mcs.NewConfig(0.03, 40, 0)
+		conf.Workers = mcs.Workers{Walkers: 3, Samplers: 3, Updaters: 2}
+
+		root := mcs.NewRoot(g.Clone(), conf)
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		result, _, err := mcs.ConcurrentSearch(ctx, root, []mcs.GamePolicy{TabooColor})
+		cancel()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		replay := mcs.GameState(g.Clone())
+		total := 0.0
+		for _, move := range result.Moves() {
+			total += move.Score()
+			replay = replay.Play(move)
+		}
+		total += replay.Score()
+
+		if total != result.Score() {
+			t.Errorf("search: replayed %g, expected %g", total, result.Score())
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2018 Erik Adelbert. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package samegame
+
+import "mcs/pkg/chaingame"
+
+// Coding selects the features of a move that are encoded by Code.
+type Coding uint8
+
+const (
+	// ByColor encodes the color of the tile.
+	ByColor Coding = 1 << iota
+
+	// BySize encodes the number of blocks of the tile.
+	BySize
+
+	// ByAnchor encodes the position of the anchor block of the tile.
+	ByAnchor
+
+	// DefaultCoding is used when moves are coded for mcs policies.
+	DefaultCoding = ByColor | BySize | ByAnchor
+)
+
+// Code encodes the features of a move played in the calling state. Codes are used
+// as keys of learned policies.
+//
+// The anchor of a tile is its bottom left block. As rows are removed from the top of the
+// board, the anchor row is counted from the bottom.
+func (sg State) Code(m Move, coding Coding) uint64 {
+	if m.Len() == 0 {
+		return 0
+	}
+
+	board := SameBoard(sg)
+
+	var code uint64
+	if coding&ByColor != 0 {
+		code |= uint64(board.TileColor(chaingame.Tile(m))) << 32
+	}
+
+	if coding&BySize != 0 {
+		code |= uint64(m.Len()) << 16
+	}
+
+	if coding&ByAnchor != 0 {
+		anchor := m[0]
+		for _, block := range m[1:] {
+			if block.Row() > anchor.Row() || (block.Row() == anchor.Row() && block.Column() < anchor.Column()) {
+				anchor = block
+			}
+		}
+
+		h, _ := board.Dims()
+		code |= uint64(h-1-anchor.Row())<<8 | uint64(anchor.Column())
+	}
+
+	return code
+}
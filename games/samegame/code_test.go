@@ -0,0 +1,69 @@
+package samegame
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"mcs/pkg/chaingame"
+	"mcs/pkg/mcs"
+)
+
+func TestState_Code(t *testing.T) {
+	b := NewSameBoard(3, 3)
+	b.Load([]string{
+		"VBB",
+		"VVB",
+		"BVV",
+	})
+	state := State(b)
+
+	var violet, blue Move
+	for _, move := range state.Moves().List() {
+		switch b.TileColor(chaingame.Tile(move)) {
+		case chaingame.Violet:
+			violet = move
+		case chaingame.Blue:
+			blue = move
+		}
+	}
+
+	if c := state.Code(violet, ByColor); c != uint64(chaingame.Violet)<<32 {
+		t.Errorf("code: wrong violet color code %x", c)
+	}
+
+	if c := state.Code(violet, BySize); c != 5<<16 {
+		t.Errorf("code: wrong violet size code %x", c)
+	}
+
+	// violet anchor is (2, 1), blue anchor is (1, 2)
+	if c := state.Code(violet, ByAnchor); c != 0<<8|1 {
+		t.Errorf("code: wrong violet anchor code %x", c)
+	}
+
+	if c := state.Code(blue, ByAnchor); c != 1<<8|2 {
+		t.Errorf("code: wrong blue anchor code %x", c)
+	}
+
+	if c := state.Code(violet, DefaultCoding); c != uint64(chaingame.Violet)<<32|5<<16|0<<8|1 {
+		t.Errorf("code: wrong violet default code %x", c)
+	}
+}
+
+func TestGameState_SampleWeighted(t *testing.T) {
+	g := newTestState()
+
+	score, moves := g.Clone().Sample(context.Background(), rand.New(rand.NewSource(1)), mcs.Weights{})
+
+	replay := mcs.GameState(g.Clone())
+	total := 0.0
+	for _, move := range moves {
+		total += move.Score()
+		replay = replay.Play(move)
+	}
+	total += replay.Score()
+
+	if total != score {
+		t.Errorf("sample: replayed %g, expected %g", total, score)
+	}
+}
@@ -5,6 +5,9 @@
 package samegame
 
 import (
+	"context"
+	"math/rand"
+
 	"mcs/pkg/chaingame"
 )
 
@@ -12,9 +15,9 @@ import (
 type Hand chaingame.ColorTiles
 
 // Draw randomly removes a move from the hand.
-func (h Hand) Draw() (Move, Hand) {
+func (h Hand) Draw(rng *rand.Rand) (Move, Hand) {
 	tiles := chaingame.ColorTiles(h)
-	tile := tiles.PickTile(chaingame.NoColor)
+	tile := tiles.PickTile(rng, chaingame.NoColor)
 
 	return Move(tile), Hand(tiles)
 }
@@ -111,8 +114,14 @@ func (sg State) Play(m Move) State {
 }
 
 // Sample simulates a game to its end by applying a move selection policy. The policy usually
-// embeds randomness.
-func (sg State) Sample(done <-chan struct{}, policy ColorPolicy) (float64, Sequence) {
+// embeds randomness. Sampling stops early when ctx is done. Moves are chosen uniformly.
+func (sg State) Sample(ctx context.Context, rng *rand.Rand, policy ColorPolicy) (float64, Sequence) {
+	return sg.SampleBy(ctx, rng, policy, nil)
+}
+
+// SampleBy is Sample with moves chosen with a probability proportional to their weight.
+// A nil weighting stands for uniform choices.
+func (sg State) SampleBy(ctx context.Context, rng *rand.Rand, policy ColorPolicy, weight chaingame.Weighting) (float64, Sequence) {
 
 	board := SameBoard(sg)
 	tiles := board.ColorTiles()
@@ -125,7 +134,8 @@ func (sg State) Sample(done <-chan struct{}, policy ColorPolicy) (float64, Seque
 	var seq Sequence
 	var score float64
 
-	for len(tiles) > 0 {
+	done := ctx.Done()
+	for tiles.Len(chaingame.AllColors) > 0 {
 		select {
 		case <-done:
 			return score, seq
@@ -133,7 +143,12 @@ func (sg State) Sample(done <-chan struct{}, policy ColorPolicy) (float64, Seque
 			if c, mode := policy(board); mode == PerMove {
 				taboo = c
 			}
-			tile := tiles.RandomTile(taboo)
+			var tile chaingame.Tile
+			if weight == nil {
+				tile = tiles.RandomTile(rng, taboo)
+			} else {
+				tile = tiles.WeightedTile(rng, taboo, weight)
+			}
 
 			board = board.Remove(tile)
 			tiles = board.ColorTiles()
@@ -24,6 +24,14 @@ const (
 // ColorPolicy is used for selecting nodes and moves during playouts.
 type ColorPolicy func(SameBoard) (chaingame.Color, Mode)
 
+// WeightedPolicy asks for moves to be chosen with a probability proportional to
+// their weight, eg. chaingame.TileSize, instead of uniformly. A nil Taboo stands
+// for NoTaboo.
+type WeightedPolicy struct {
+	Taboo  ColorPolicy
+	Weight chaingame.Weighting
+}
+
 // NoTaboo deactivate taboo selection.
 func NoTaboo(board SameBoard) (chaingame.Color, Mode) {
 	_ = board
@@ -34,8 +42,8 @@ func NoTaboo(board SameBoard) (chaingame.Color, Mode) {
 func TabooColor(board SameBoard) (chaingame.Color, Mode) {
 
 	taboo, max := chaingame.NoColor, 0.0
-	for c, n := range board.Histogram {
-		if n > max {
+	for c := chaingame.Red; c < chaingame.AllColors; c++ { // not in map order
+		if n := board.Histogram[c]; n > max {
 			taboo, max = c, n
 		}
 	}
@@ -5,6 +5,7 @@
 package samegame
 
 import (
+	"math/rand"
 	"strings"
 
 	"mcs/pkg/chaingame"
@@ -39,13 +40,17 @@ func (sb SameBoard) Clone() SameBoard {
 	return SameBoard{board, histo}
 }
 
-// Load a SameBoard from strings and initializes its histogram.
-func (sb SameBoard) Load(buf []string) {
-	sb.Board.Load(buf)
+// Load a SameBoard from strings and initializes its histogram, see chaingame.Board.Load.
+func (sb SameBoard) Load(buf []string) error {
+	if err := sb.Board.Load(buf); err != nil {
+		return err
+	}
 
 	for k, v := range sb.Board.Histogram() {
 		sb.Histogram[k] = v
 	}
+
+	return nil
 }
 
 // Randomize the SameBoard  with n colors :
@@ -53,9 +58,9 @@ func (sb SameBoard) Load(buf []string) {
 //  - passing 'AllColors' adds one of every color to the list.
 //  - a one color list produces a board filled with a unique tile.
 //
-// c.Randomize(AllColors, Red, Indigo)
-func (sb SameBoard) Randomize(list ...chaingame.Color) {
-	sb.Board.Randomize(list...)
+// c.Randomize(rng, AllColors, Red, Indigo)
+func (sb SameBoard) Randomize(rng *rand.Rand, list ...chaingame.Color) {
+	sb.Board.Randomize(rng, list...)
 
 	for k, v := range sb.Board.Histogram() {
 		sb.Histogram[k] = v
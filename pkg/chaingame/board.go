@@ -5,6 +5,7 @@
 package chaingame
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
 	"strings"
@@ -109,24 +110,63 @@ func (b Board) Len() int {
 	return h * w
 }
 
-// Load a board from a slice of strings.
-func (b Board) Load(buf []string) {
+// ErrInvalidBoard is returned when a loaded board doesn't fit, see BoardError.
+var ErrInvalidBoard = errors.New("chaingame: invalid board")
+
+// BoardError locates the first flaw of a loaded board, it wraps ErrInvalidBoard.
+type BoardError struct {
+	Row, Col int
+	Reason   string
+}
+
+func (e *BoardError) Error() string {
+	return fmt.Sprintf("%v: row %d, column %d: %s", ErrInvalidBoard, e.Row, e.Col, e.Reason)
+}
+
+func (e *BoardError) Unwrap() error {
+	return ErrInvalidBoard
+}
+
+// Load a board from a slice of strings, one per row. Rows must fill the board
+// and blocks are given by color letters, '-' stands for an empty cell.
+// The board is left untouched when invalid.
+func (b Board) Load(buf []string) error {
+	h, w := b.Dims()
+
+	if len(buf) != h {
+		return &BoardError{Row: len(buf), Reason: fmt.Sprintf("%d rows, expected %d", len(buf), h)}
+	}
 
 	for i, row := range buf {
-		for j, c := range row {
+		j := 0
+		for _, c := range row {
+			if j < w && NewColor(c) == NoColor && c != '-' {
+				return &BoardError{Row: i, Col: j, Reason: fmt.Sprintf("unknown color %q", c)}
+			}
+			j++
+		}
+
+		if j != w {
+			return &BoardError{Row: i, Col: j, Reason: fmt.Sprintf("%d columns, expected %d", j, w)}
+		}
+	}
+
+	for i, row := range buf {
+		j := 0
+		for _, c := range row {
 			b[i][j] = NewColor(c)
+			j++
 		}
 	}
 
-	return
+	return nil
 }
 
 // Randomize a board with n colors :
 //  - repeating a color modifies the distribution accordingly.
 //  - listing 'AllColors' adds one of every color to the list.
 //  - a one color list produces a board filled with a unique tile.
-func (b Board) Randomize(list ...Color) {
-	//rand.Seed(time.Now().UnixNano())
+func (b Board) Randomize(rng *rand.Rand, list ...Color) {
 	colors := make([]Color, 0, AllColors)
 
 	for _, color := range list {
@@ -142,7 +182,7 @@ func (b Board) Randomize(list ...Color) {
 
 	for i, row := range b {
 		for j := range row {
-			b[i][j] = colors[rand.Intn(len(colors))]
+			b[i][j] = colors[rng.Intn(len(colors))]
 		}
 	}
 }
@@ -251,9 +291,7 @@ func (b Board) String() string {
 	}
 
 	for i, row := range b {
-		if _, err := fmt.Fprintf(&sb, "%3d: ", i); err != nil {
-			panic("board: can't string")
-		}
+		fmt.Fprintf(&sb, "%3d: ", i)
 		for _, block := range row {
 			sb.WriteString(block.String())
 		}
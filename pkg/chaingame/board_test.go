@@ -1,6 +1,11 @@
 package chaingame
 
-import "testing"
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+)
 
 func TestNewBoard(t *testing.T) {
 
@@ -31,7 +36,44 @@ func TestBoard_Len(t *testing.T) {
 }
 
 func TestBoard_Load(t *testing.T) {
+	b := NewBoard(2, 3)
+	if err := b.Load([]string{"RG-", "BVY"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if b[0][1] != Green || b[0][2] != NoColor || b[1][2] != Yellow {
+		t.Errorf("load: unexpected board\n%v", b)
+	}
+
+	tests := []struct {
+		buf      []string
+		row, col int
+	}{
+		{[]string{"RGB"}, 1, 0},
+		{[]string{"RGB", "RGB", "RGB"}, 3, 0},
+		{[]string{"RGB", "RG"}, 1, 2},
+		{[]string{"RGB", "RGBV"}, 1, 4},
+		{[]string{"RGB", "RxB"}, 1, 1},
+	}
+
+	for _, test := range tests {
+		b := NewBoard(2, 3)
+
+		err := b.Load(test.buf)
+		if !errors.Is(err, ErrInvalidBoard) {
+			t.Errorf("load: %q, expected %v, got %v", test.buf, ErrInvalidBoard, err)
+			continue
+		}
 
+		var e *BoardError
+		if !errors.As(err, &e) || e.Row != test.row || e.Col != test.col {
+			t.Errorf("load: %q, expected flaw at %d,%d, got %v", test.buf, test.row, test.col, err)
+		}
+
+		if len(b.Histogram()) != 0 {
+			t.Errorf("load: %q, invalid board loaded", test.buf)
+		}
+	}
 }
 
 func TestBoard_Randomize(t *testing.T) {
@@ -41,7 +83,7 @@ func TestBoard_Randomize(t *testing.T) {
 func TestBoard_Remove(t *testing.T) {
 	h, w := 20, 10
 	board := NewBoard(h, w)
-	board.Randomize(AllColors)
+	board.Randomize(rand.New(rand.NewSource(1)), AllColors)
 	t.Log(board)
 
 	tiles := board.Tiles()
@@ -60,7 +102,7 @@ func TestBoard_Remove(t *testing.T) {
 func TestBoard_RemoveAll(t *testing.T) {
 	h, w := 20, 10
 	board := NewBoard(h, w)
-	board.Randomize(Red)
+	board.Randomize(rand.New(rand.NewSource(1)), Red)
 	t.Log(board)
 
 	tiles := board.Tiles()
@@ -83,3 +125,15 @@ func TestBoard_String(t *testing.T) {
 func TestBoard_Tiles(t *testing.T) {
 
 }
+
+func TestBoard_Tiles_reproducible(t *testing.T) {
+	board := NewBoard(20, 10)
+	board.Randomize(rand.New(rand.NewSource(1)), AllColors)
+
+	first := fmt.Sprint(board.Tiles(), board.Clone().ColorTiles().Tiles(AllColors))
+	for i := 0; i < 10; i++ {
+		if again := fmt.Sprint(board.Tiles(), board.Clone().ColorTiles().Tiles(AllColors)); again != first {
+			t.Fatalf("tiles are not listed in a reproducible order")
+		}
+	}
+}
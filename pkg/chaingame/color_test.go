@@ -68,5 +68,5 @@ func TestColor_String(t *testing.T) {
 		}
 	}
 
-	t.Logf(sb.String())
+	t.Log(sb.String())
 }
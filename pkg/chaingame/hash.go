@@ -0,0 +1,34 @@
+// Copyright 2018 Erik Adelbert. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package chaingame
+
+// Hash returns a Zobrist hash of the board: keys of colored cells and of the board
+// dimensions are xored. Keys are derived from a fixed seed, hashes are stable across
+// runs. Equal boards have equal hashes.
+// see https://en.wikipedia.org/wiki/Zobrist_hashing
+func (b Board) Hash() uint64 {
+	h, w := b.Dims()
+
+	hash := zobrist(uint64(h)<<32 | uint64(w) | 1<<63)
+	for i, row := range b {
+		for j, color := range row {
+			if color != NoColor {
+				hash ^= zobrist(uint64(i)<<24 | uint64(j)<<8 | uint64(color))
+			}
+		}
+	}
+
+	return hash
+}
+
+// zobrist returns the random key of a cell. It's the splitmix64 finalizer applied
+// to the cell coordinates and color: keys are computed instead of being stored.
+// see http://xoshiro.di.unimi.it/splitmix64.c
+func zobrist(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
@@ -0,0 +1,45 @@
+package chaingame
+
+import "testing"
+
+func TestBoard_Hash(t *testing.T) {
+	b := NewBoard(3, 3)
+	b.Load([]string{
+		"RGG",
+		"RBB",
+		"GGB",
+	})
+
+	if b.Hash() != b.Clone().Hash() {
+		t.Errorf("hash: equal boards have different hashes")
+	}
+
+	// Removing (0,0) then the blue tile or the blue tile then (0,0) reaches
+	// the same board.
+	var red, blue Tile
+	for _, tile := range b.Tiles() {
+		switch b[tile[0].r][tile[0].c] {
+		case Red:
+			red = tile
+		case Blue:
+			blue = tile
+		}
+	}
+
+	x := b.Clone().Remove(red).Remove(blue)
+	y := b.Clone().Remove(blue)
+	for _, tile := range y.Tiles() { // red tile has moved
+		if y[tile[0].r][tile[0].c] == Red {
+			y = y.Remove(tile)
+		}
+	}
+
+	if x.Hash() != y.Hash() {
+		t.Errorf("hash: transposed boards have different hashes\n%v\n%v", x, y)
+	}
+
+	z := b.Clone().Remove(red)
+	if z.Hash() == x.Hash() || z.Hash() == b.Hash() {
+		t.Errorf("hash: different boards share a hash")
+	}
+}
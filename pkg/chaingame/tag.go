@@ -4,6 +4,8 @@
 
 package chaingame
 
+import "sort"
+
 // A tag is composed of an ID and a color. It's used when tiling
 // boards. It uniquely identifies blocks of a same group.
 type tag int
@@ -31,10 +33,17 @@ func newTags(cap int) tags {
 	return make(tags, cap)
 }
 
-// List all tags.
-func (t tags) List() tags {
+// List all tags in increasing order.
+func (t tags) List() []tag {
 	delete(t, 0) // Remove id facility
-	return t
+
+	list := make([]tag, 0, len(t))
+	for tag := range t {
+		list = append(list, tag)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+
+	return list
 }
 
 // NewID returns a unique incrementing ID.
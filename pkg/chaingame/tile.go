@@ -7,6 +7,7 @@ package chaingame
 import (
 	"fmt"
 	"math/rand"
+	"sort"
 )
 
 type (
@@ -26,14 +27,24 @@ func (t Tile) String() string {
 	return fmt.Sprintf("%v{%d}", t[0], len(t))
 }
 
-// ColorTiles is set of tiles grouped by color.
-type ColorTiles map[Color]Tiles
+// ColorTiles is a set of tiles grouped by color, groups are indexed by color.
+// Iterations follow the colors order: there's no randomness from map iteration,
+// random choices are drawn from injected generators.
+type ColorTiles [AllColors]Tiles
 
-// Colors lists all the colors present in a set of tiles.
+// Weighting gives the relative chance of a tile to be chosen at random.
+type Weighting func(Tile) float64
+
+// TileSize weighs a tile by its number of blocks.
+func TileSize(t Tile) float64 {
+	return float64(len(t))
+}
+
+// Colors lists all the colors present in a set of tiles in increasing order.
 func (c ColorTiles) Colors() []Color {
-	colors := make([]Color, 0, len(c))
-	for color := range c {
-		if color != NoColor {
+	colors := make([]Color, 0, AllColors)
+	for color := Red; color < AllColors; color++ {
+		if len(c[color]) > 0 {
 			colors = append(colors, color)
 		}
 	}
@@ -43,7 +54,7 @@ func (c ColorTiles) Colors() []Color {
 // Histogram computes counts of blocks by color.
 func (c ColorTiles) Histogram() Histogram {
 	colors := make(Histogram, int(AllColors))
-	for color := range c {
+	for _, color := range c.Colors() {
 		tlen := 0
 		for _, tile := range c.Tiles(color) {
 			tlen += len(tile)
@@ -68,48 +79,64 @@ func (c ColorTiles) Len(color Color) int {
 	return len(c[color])
 }
 
-// PickTile removes a random tile from the set when called with 'NoColor'
-// or from the corresponding subset when a color is specified.
-func (c ColorTiles) PickTile(taboo Color) Tile {
+// PickTile removes, if possible, a tile chosen uniformly at random which isn't of
+// taboo color from the set. Any tile may be removed when called with 'NoColor'.
+func (c *ColorTiles) PickTile(rng *rand.Rand, taboo Color) Tile {
 
-	if c == nil {
+	color, i := c.random(rng, taboo)
+	if color == NoColor {
 		return nil
 	}
 
-	var color Color
-	for color = range c { // ColorTiles are randomized by construction
-		if color != taboo || taboo == NoColor {
-			break
-		}
-	}
+	tiles := c[color]
 
-	var tile Tile
-	if tiles := c[color]; len(tiles) > 0 { // Tiles are randomized by construction
-		tile, tiles[0] = tiles[0], nil
+	last, tile := len(tiles)-1, tiles[i]
+	tiles[i], tiles[last] = tiles[last], nil
+	c[color] = tiles[:last]
 
-		if len(tiles) == 1 {
-			delete(c, color)
-		} else {
-			c[color] = tiles[1:]
-		}
+	return tile
+}
+
+// RandomTile chooses, if possible, a tile uniformly at random which isn't of taboo
+// color. Returns a random tile of any color if called with 'NoColor'.
+func (c ColorTiles) RandomTile(rng *rand.Rand, taboo Color) Tile {
+
+	color, i := c.random(rng, taboo)
+	if color == NoColor {
+		return nil
 	}
 
-	return tile
+	return c[color][i]
 }
 
-// RandomTile chooses, if possible,  a tile at random which isn't of taboo color.
-// Returns a random color tile if called with 'NoColor'
-func (c ColorTiles) RandomTile(taboo Color) Tile {
+// WeightedTile is RandomTile with a probability proportional to the weight of tiles.
+func (c ColorTiles) WeightedTile(rng *rand.Rand, taboo Color, weight Weighting) Tile {
+
+	excluded := c.excluded(taboo)
 
-	var color Color
-	for color = range c { // Colors are randomized by construction
-		if taboo != color || taboo == NoColor {
-			break
+	total := 0.0
+	for color := Red; color < AllColors; color++ {
+		if color != excluded {
+			for _, tile := range c[color] {
+				total += weight(tile)
+			}
+		}
+	}
+
+	var chosen Tile
+
+	x := rng.Float64() * total
+	for color := Red; color < AllColors; color++ {
+		if color != excluded {
+			for _, chosen = range c[color] {
+				if x -= weight(chosen); x < 0 {
+					return chosen
+				}
+			}
 		}
 	}
-	tiles := c[color]
 
-	return tiles[rand.Intn(len(tiles))]
+	return chosen // rounding errors
 }
 
 // Tiles lists tiles of a color group. It lists all tiles
@@ -128,25 +155,60 @@ func (c ColorTiles) Tiles(color Color) Tiles {
 
 }
 
+// excluded returns the color out of random choices: taboo, unless its tiles are
+// the only ones left.
+func (c ColorTiles) excluded(taboo Color) Color {
+	if taboo >= AllColors || c.Len(AllColors) == len(c[taboo]) {
+		return NoColor
+	}
+	return taboo
+}
+
+// random locates a tile chosen uniformly at random, its color is 'NoColor'
+// when there's none.
+func (c ColorTiles) random(rng *rand.Rand, taboo Color) (Color, int) {
+	excluded := c.excluded(taboo)
+
+	n := c.Len(AllColors) - len(c[excluded])
+	if n == 0 {
+		return NoColor, 0
+	}
+
+	k := rng.Intn(n)
+	for color := Red; color < AllColors; color++ {
+		if color == excluded {
+			continue
+		}
+
+		if k < len(c[color]) {
+			return color, k
+		}
+		k -= len(c[color])
+	}
+
+	return NoColor, 0
+}
+
 // taggedTiles are used to extract examples tiles (connected components)
 type taggedTiles map[tag]Tile
 
 // Colormap groups taggedTiles by color
 func (t taggedTiles) Colormap(b Board) ColorTiles {
 
+	var m ColorTiles
+
 	if b.Len() == 0 {
-		return nil
+		return m
 	}
 
 	t.build(b)
 
-	m := make(ColorTiles, int(AllColors))
-	for tag, tile := range t { // Randomized by construction
-		if len(tile) > 1 {
+	for _, tag := range t.sorted() {
+		if tile := t[tag]; len(tile) > 1 {
 			color := tag.Color()
 
-			tiles, ok := m[color]
-			if !ok {
+			tiles := m[color]
+			if tiles == nil {
 				tiles = make(Tiles, 0, 4)
 			}
 			m[color] = append(tiles, tile)
@@ -167,8 +229,8 @@ func (t taggedTiles) List(b Board) Tiles {
 	t.build(b)
 
 	tiles := make(Tiles, 0, len(t))
-	for tag, tile := range t { // Randomized by construction
-		if len(tile) > 1 {
+	for _, tag := range t.sorted() {
+		if tile := t[tag]; len(tile) > 1 {
 			tiles = append(tiles, tile)
 		}
 		delete(t, tag)
@@ -176,6 +238,18 @@ func (t taggedTiles) List(b Board) Tiles {
 	return tiles
 }
 
+// sorted lists the tags in increasing order: tiles are listed in a reproducible
+// order, not in map order.
+func (t taggedTiles) sorted() []tag {
+	list := make([]tag, 0, len(t))
+	for tag := range t {
+		list = append(list, tag)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+
+	return list
+}
+
 // Board tiling is done efficiently in a Hoshen–Kopelman manner:
 // 1) The board is copied into a larger buffer that supports labeling
 //    while simplifying corners and borders handling.
@@ -245,7 +319,7 @@ func (t taggedTiles) extract(array [][]tag) {
 	}
 
 	// 3 - Merge tiles according to previous unifications.
-	for tag := range tags.List() {
+	for _, tag := range tags.List() {
 
 		root := tags.Find(tag)
 		if root == tag {
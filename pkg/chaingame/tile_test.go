@@ -1,6 +1,9 @@
 package chaingame
 
-import "testing"
+import (
+	"math/rand"
+	"testing"
+)
 
 func TestTile_String(t *testing.T) {
 
@@ -18,12 +21,131 @@ func TestColorTiles_Len(t *testing.T) {
 
 }
 
-func TestColorTiles_PickTile(t *testing.T) {
+// newTestTiles returns a red tile of size 2 and green tiles of sizes 3, 4 and 5.
+// A tile is identified by the row of its blocks, ie. its index in Tiles(AllColors).
+func newTestTiles() ColorTiles {
+	tile := func(id, size int) Tile {
+		t := make(Tile, size)
+		for i := range t {
+			t[i] = block{id, i}
+		}
+		return t
+	}
+
+	var c ColorTiles
+	c[Red] = Tiles{tile(0, 2)}
+	c[Green] = Tiles{tile(1, 3), tile(2, 4), tile(3, 5)}
+	return c
+}
+
+// chiSquare is Pearson's statistic of observed counts against expected frequencies.
+func chiSquare(counts []float64, freqs []float64, n float64) float64 {
+	χ2 := 0.0
+	for i, count := range counts {
+		e := freqs[i] * n
+		χ2 += (count - e) * (count - e) / e
+	}
+	return χ2
+}
+
+// Critical values of the χ² distribution at the 0.1% level by degrees of freedom.
+var χ2Critical = []float64{1: 10.83, 2: 13.82, 3: 16.27}
 
+func TestColorTiles_PickTile(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	const n = 40000
+	counts := make([]float64, 4)
+	for i := 0; i < n; i++ {
+		c := newTestTiles()
+		counts[c.PickTile(rng, NoColor)[0].r]++
+	}
+
+	if χ2 := chiSquare(counts, []float64{0.25, 0.25, 0.25, 0.25}, n); χ2 > χ2Critical[3] {
+		t.Errorf("pick: not uniform %v (χ² = %g)", counts, χ2)
+	}
+
+	c, picked := newTestTiles(), make(map[int]bool)
+	for i := 0; i < 4; i++ {
+		picked[c.PickTile(rng, Green)[0].r] = true
+	}
+
+	if len(picked) != 4 || c.Len(AllColors) != 0 || c.PickTile(rng, NoColor) != nil {
+		t.Errorf("pick: tiles are not removed once %v, %v left", picked, c)
+	}
 }
 
 func TestColorTiles_RandomTile(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	const n = 40000
+
+	cases := []struct {
+		taboo Color
+		freqs []float64
+	}{
+		{NoColor, []float64{0.25, 0.25, 0.25, 0.25}},
+		{Red, []float64{0, 1. / 3, 1. / 3, 1. / 3}},
+		{Green, []float64{1, 0, 0, 0}},
+	}
+
+	c := newTestTiles()
+	for _, tc := range cases {
+		counts := make([]float64, 4)
+		for i := 0; i < n; i++ {
+			counts[c.RandomTile(rng, tc.taboo)[0].r]++
+		}
+
+		var observed, freqs []float64
+		for i, f := range tc.freqs {
+			if f == 0 {
+				if counts[i] > 0 {
+					t.Errorf("random: taboo %v, tile #%d chosen", tc.taboo, i)
+				}
+				continue
+			}
+			observed, freqs = append(observed, counts[i]), append(freqs, f)
+		}
+
+		if df := len(freqs) - 1; df > 0 {
+			if χ2 := chiSquare(observed, freqs, n); χ2 > χ2Critical[df] {
+				t.Errorf("random: taboo %v, not uniform %v (χ² = %g)", tc.taboo, counts, χ2)
+			}
+		}
+	}
+
+	// The taboo color is chosen when nothing else is left.
+	c[Red] = nil
+	if tile := c.RandomTile(rng, Green); tile == nil {
+		t.Errorf("random: no tile chosen among taboo tiles")
+	}
+
+	if tile := (ColorTiles{}).RandomTile(rng, NoColor); tile != nil {
+		t.Errorf("random: tile %v chosen in an empty set", tile)
+	}
+}
+
+func TestColorTiles_WeightedTile(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	const n = 40000
+	counts := make([]float64, 4)
+
+	c := newTestTiles()
+	for i := 0; i < n; i++ {
+		counts[c.WeightedTile(rng, NoColor, TileSize)[0].r]++
+	}
+
+	freqs := []float64{2. / 14, 3. / 14, 4. / 14, 5. / 14}
+	if χ2 := chiSquare(counts, freqs, n); χ2 > χ2Critical[3] {
+		t.Errorf("weighted: not proportional to sizes %v (χ² = %g)", counts, χ2)
+	}
 
+	for i := 0; i < 1000; i++ {
+		if tile := c.WeightedTile(rng, Green, TileSize); tile[0].r != 0 {
+			t.Fatalf("weighted: taboo tile %v chosen", tile)
+		}
+	}
 }
 
 func TestColorTiles_Tiles(t *testing.T) {
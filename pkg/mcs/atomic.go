@@ -0,0 +1,88 @@
+// Copyright 2018 Erik Adelbert. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements the lock-free statistics of nodes: walkers read them at
+// every selection while updaters write them.
+
+package mcs
+
+import (
+	"math"
+	"runtime"
+	"sync/atomic"
+)
+
+// atomicFloat is a float64 read and written atomically.
+type atomicFloat uint64
+
+func (f *atomicFloat) load() float64 {
+	return math.Float64frombits(atomic.LoadUint64((*uint64)(f)))
+}
+
+func (f *atomicFloat) store(value float64) {
+	atomic.StoreUint64((*uint64)(f), math.Float64bits(value))
+}
+
+// seqlock lets readers take consistent snapshots of several atomic fields without
+// locking. Writers are serialized by another lock, they bump the sequence before
+// and after writing: it is odd while a write is in progress. Readers retry when
+// the sequence has changed during their read.
+// see https://en.wikipedia.org/wiki/Seqlock
+type seqlock struct {
+	seq uint32
+}
+
+// begin and end enclose a write.
+func (s *seqlock) begin() {
+	atomic.AddUint32(&s.seq, 1)
+}
+
+func (s *seqlock) end() {
+	atomic.AddUint32(&s.seq, 1)
+}
+
+// read waits for the end of a write in progress and returns the sequence to check
+// with retry once the fields are read.
+func (s *seqlock) read() uint32 {
+	for {
+		if seq := atomic.LoadUint32(&s.seq); seq&1 == 0 {
+			return seq
+		}
+		runtime.Gosched()
+	}
+}
+
+// retry is true when the fields have been written since the given sequence.
+func (s *seqlock) retry(seq uint32) bool {
+	return atomic.LoadUint32(&s.seq) != seq
+}
+
+// statistics is a consistent snapshot of the statistics of a node.
+type statistics struct {
+	visits   float64
+	mean     float64
+	variance float64 // sum of squared deviations, see Variance
+	worst    float64
+	best     float64 // score of the best decision
+}
+
+// statistics returns a snapshot of the statistics of the calling node, it doesn't
+// lock the node.
+func (n *Node) statistics() (s statistics) {
+	for {
+		seq := n.seq.read()
+
+		s = statistics{
+			visits:   n.visits.load(),
+			mean:     n.mean.load(),
+			variance: n.variance.load(),
+			worst:    n.worst.load(),
+			best:     n.top.load(),
+		}
+
+		if !n.seq.retry(seq) {
+			return
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package mcs
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func TestNode_statistics(t *testing.T) {
+	node := NewRoot(newToy(1, 2), NewConfig(0.03, 40, 0))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	for i := 0; i < 2; i++ {
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			for j := 0; j < 2000; j++ {
+				node.update(Decision{score: rng.Float64() * 100})
+			}
+		}(int64(i))
+	}
+
+	// Snapshots are consistent while the node is updated.
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+
+		s := node.statistics()
+		if s.visits == 0 {
+			continue
+		}
+		if s.worst > s.mean || s.mean > s.best || s.variance < 0 {
+			t.Fatalf("statistics: inconsistent snapshot %+v", s)
+		}
+	}
+
+	if s := node.statistics(); s.visits != 4000 || s.best != node.Best().Score() {
+		t.Errorf("statistics: expected 4000 visits and best %g, got %+v", node.Best().Score(), s)
+	}
+}
@@ -154,9 +154,7 @@ func (c cmd) Count() {
 func (c cmd) Down() {
 	var sb strings.Builder
 	for i := 0; i < len(node.down); i++ {
-		if _, err := fmt.Fprintf(&sb, "%2d: %v\t@%p\t", i, node.down[i].edge, node.down[i]); err != nil {
-			panic(err)
-		}
+		fmt.Fprintf(&sb, "%2d: %v\t@%p\t", i, node.edgeTo(node.down[i]), node.down[i])
 
 		if (i+1)%3 == 0 {
 			sb.WriteByte('\n')
@@ -241,9 +239,7 @@ func (c cmd) Ls() {
 }
 
 func (c cmd) Up() {
-	if _, err := fmt.Printf("@%p\n", node.up); err != nil {
-		panic(err)
-	}
+	fmt.Printf("@%p\n", node.up)
 }
 
 func (c cmd) ascend() {
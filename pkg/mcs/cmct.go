@@ -7,6 +7,9 @@
 package mcs
 
 import (
+	"context"
+	"fmt"
+	"math/rand"
 	"runtime"
 	"sync"
 	"time"
@@ -23,13 +26,51 @@ func numGoRoutines() int {
 	}
 }
 
-var walkers = numGoRoutines()
-var updaters = walkers
-var samplers = 2 * walkers // samplers are the slowest
+// Workers sizes the pipeline of ConcurrentSearch. Zero counts are replaced by
+// the defaults: as many walkers as updaters, twice more samplers and at least
+// 2 walkers.
+type Workers struct {
+	Walkers  int
+	Samplers int
+	Updaters int
+
+	// Adaptive lets the search move goroutines between stages: the fill levels of
+	// the channels between stages are watched and a goroutine of the stage which
+	// lets its channels run full or empty is given to the lagging stage. Counts
+	// are the initial sizes of the stages, each stage keeps at least one goroutine.
+	Adaptive bool
+}
+
+// sized fills in the default counts.
+func (w Workers) sized() Workers {
+	n := numGoRoutines()
+
+	if w.Walkers <= 0 {
+		w.Walkers = n
+	}
+	if w.Updaters <= 0 {
+		w.Updaters = n
+	}
+	if w.Samplers <= 0 {
+		w.Samplers = 2 * n // samplers are the slowest
+	}
+
+	return w
+}
+
+func (w Workers) String() string {
+	s := fmt.Sprintf("%d walkers, %d samplers, %d updaters", w.Walkers, w.Samplers, w.Updaters)
+	if w.Adaptive {
+		s += ", adaptive"
+	}
+	return s
+}
 
 // Jobs convey nodes and best moves between mcts steps (ie. walkers, samplers and updaters).
+// The path leads from the root to the node.
 type job struct {
 	node     *Node
+	path     []*Node
 	decision Decision
 }
 
@@ -41,105 +82,147 @@ type job struct {
 // see:
 // high scores are on http://www.js-games.de/eng/highscores/samegame/lx (results registered as cmct)
 // http://citeseerx.ist.psu.edu/viewdoc/download?doi=10.1.1.159.4373&rep=rep1&type=pdf
-func ConcurrentSearch(root *Node, policies []GamePolicy, duration time.Duration) Decision {
-	if root == nil {
-		// TODO: error handling
-		panic("no root")
+func ConcurrentSearch(ctx context.Context, root *Node, policies []GamePolicy) (Decision, Stats, error) {
+	if decision, err := check(root, policies); err != nil {
+		return decision, Stats{}, err
 	}
 
 	// All possible first moves are expanded.
 	tree := GrowTree(root)
 
-	done := make(chan struct{})
-	timeout := make(chan bool)
+	// The search must return a decision before the deadline of ctx. A late
+	// decision is as bad as an illegal move, it's disqualifying.
+	// The search is also stopped once solved or when its budget is exhausted.
+	ctx, track := newTracker(ctx, tree)
+	done := ctx.Done()
 
-	// Start the countdown asap: the search must return a decision
-	// in the given time frame. A late decision is as bad as an
-	// illegal move, it's disqualifying.
-	go func() {
-		time.Sleep(duration)
-		close(timeout)
-	}()
+	// Every walker and sampler owns a random generator derived from the seed.
+	seeds := newRand(tree.conf.Seed)
 
-	// Prepare pipelines (channels and goroutines launchers).
-	positions := make(chan job, samplers)
-	walk := func(count int) {
-		var wg sync.WaitGroup
-
-		wg.Add(count)
-		for i := 0; i < count; i++ {
-			go func() {
-				walker(done, tree, positions)
-				wg.Done()
-			}()
-		}
+	// Every goroutine is waited for before concluding: the tree is left
+	// untouched once the search has returned.
+	var stopped sync.WaitGroup
 
-		go func() {
-			wg.Wait()
-			close(positions)
-		}()
-	}
+	// Prepare pipelines (channels and goroutines pools).
+	workers := tree.conf.Workers.sized()
 
-	outcomes := make(chan job, samplers)
-	sample := func(count int) {
-		var wg sync.WaitGroup
+	// Balanced channels are sized after all the goroutines: any stage may grow.
+	capacity := workers.Samplers
+	if workers.Adaptive {
+		capacity = workers.Walkers + workers.Samplers + workers.Updaters
+	}
 
-		wg.Add(count)
-		for i := 0; i < count; i++ {
-			go func() {
-				sampler(done, policies, positions, outcomes)
-				wg.Done()
-			}()
+	positions := make(chan job, capacity)
+	outcomes := make(chan job, capacity)
+
+	var pools [numStages]*pool
+
+	pools[walking] = newPool(seeds, &stopped, func(rng *rand.Rand, retire <-chan struct{}) {
+		walker(done, retire, rng, tree, positions, track)
+	})
+	pools[sampling] = newPool(seeds, &stopped, func(rng *rand.Rand, retire <-chan struct{}) {
+		sampler(ctx, retire, rng, policies, positions, outcomes, track)
+	})
+	pools[updating] = newPool(nil, &stopped, func(_ *rand.Rand, retire <-chan struct{}) {
+		updater(done, retire, outcomes, track)
+	})
+
+	track.watch("positions", func() int { return len(positions) }, cap(positions))
+	track.watch("outcomes", func() int { return len(outcomes) }, cap(outcomes))
+	track.staff(func() Workers {
+		return Workers{
+			Walkers:  pools[walking].len(),
+			Samplers: pools[sampling].len(),
+			Updaters: pools[updating].len(),
+			Adaptive: workers.Adaptive,
 		}
+	})
 
-		go func() {
-			wg.Wait()
-			close(outcomes)
-		}()
-	}
-
-	update := func(count int) {
-		for i := 0; i < count; i++ {
-			go func() {
-				updater(done, outcomes)
-			}()
+	// The balancer holds the pools open while it may spawn goroutines.
+	if workers.Adaptive {
+		for _, p := range pools {
+			p.hold()
 		}
 	}
 
 	// Launch!
-	go update(updaters)
-	go sample(samplers)
-	go walk(walkers)
+	pools[updating].spawn(workers.Updaters)
+	pools[sampling].spawn(workers.Samplers)
+	pools[walking].spawn(workers.Walkers)
 
-	// Wait for either timeout or solution
+	// Channels are closed once their writers are gone.
+	go func() {
+		pools[walking].wait()
+		close(positions)
+	}()
+
+	go func() {
+		pools[sampling].wait()
+		close(outcomes)
+	}()
+
+	if workers.Adaptive {
+		stopped.Add(1)
+		go func() {
+			balance(done, &pools, positions, outcomes)
+			for _, p := range pools {
+				p.release()
+			}
+			stopped.Done()
+		}()
+	}
+
+	// Wait for either deadline, cancellation or solution
 	for {
 		select {
-		case <-timeout:
+		case <-done:
 			goto conclusion
 		default:
-			if root.IsSolved() {
+			if tree.IsSolved() {
 				goto conclusion
 			}
 			runtime.Gosched()
 		}
 	}
 
-	// Broadcast termination message (done) to all goroutines
-	// and return the best sequence found so far.
+	// Broadcast termination message (done) to all goroutines, wait
+	// for them to stop and return the best sequence found so far.
 conclusion:
-	close(done)
-	return tree.Best()
+	track.stop()
+	stopped.Wait()
+
+	// Jobs left in the pipeline are given up.
+	for _, ch := range []chan job{positions, outcomes} {
+		for len(ch) > 0 {
+			forget((<-ch).path)
+		}
+	}
+
+	return decide(tree), track.stats(), nil
 }
 
 // A sampler is the slowest performer of the asynchronous pipeline. This is why there are twice
 // more samplers than other kinds of goroutine: the assumption is that loading up the pipeline
 // with simulation will eventually reduce dead time in walkers and updaters.
-func sampler(done <-chan struct{}, policies []GamePolicy, position <-chan job, outcome chan<- job) {
+// A sampler retires between two simulations, or gives up the simulation it can't pass along.
+func sampler(ctx context.Context, retire <-chan struct{}, rng *rand.Rand, policies []GamePolicy, position <-chan job, outcome chan<- job, track *tracker) {
+	done := ctx.Done()
 
-	for task := range position {
-		node, decision := task.node, task.decision
+	for {
+		var task job
+		select {
+		case <-retire:
+			return
+		case next, ok := <-position:
+			if !ok {
+				return
+			}
+			task = next
+		}
+		node, path, decision := task.node, task.path, task.decision
 
 		if node == nil {
+			forget(path)
 			continue
 		}
 
@@ -153,82 +236,141 @@ func sampler(done <-chan struct{}, policies []GamePolicy, position <-chan job, o
 			//log.Printf("sampler: %v node %p\n", node.Status(), node)
 		default:
 			//log.Printf("sampler: discarding already %v node %p\n", node.Status(), node)
+			forget(path)
 			continue
 		}
 
-		sampled := decision.Join(state.Sample(done, policies[0]))
+		start := time.Now()
+		sampled := decision.Join(simulate(ctx, rng, state, policies[0]))
+		track.spent(sampling, start)
+
+		// The status is set before passing the outcome along: an updater may
+		// reset it to idle as soon as it's sent.
+		node.SetStatus(simulated)
 
 		select {
 		case <-done:
+			forget(path)
+			return
+		case <-retire:
+			node.SetStatus(idle) // given up
+			forget(path)
 			return
-		case outcome <- job{node, sampled}:
-			node.SetStatus(simulated)
+		case outcome <- job{node, path, sampled}:
 		}
 	}
 }
 
 // An updater is asynchronously back propagating scores received from simulating.
-// It computes UCB values along the way.
-func updater(done <-chan struct{}, outcome <-chan job) {
+// It computes UCB values along the way and reports progress. It retires between two updates.
+func updater(done <-chan struct{}, retire <-chan struct{}, outcomes <-chan job, track *tracker) {
+
+	for {
+		var outcome job
+		select {
+		case <-retire:
+			return
+		case next, ok := <-outcomes:
+			if !ok {
+				return
+			}
+			outcome = next
+		}
 
-	for outcome := range outcome {
 		select {
 		case <-done:
+			forget(outcome.path)
 			return
 		default:
-			node, decision := outcome.node, outcome.decision
+			node, path, decision := outcome.node, outcome.path, outcome.decision
 			if node != nil {
 				//log.Printf("updater: updating %v node %p", node.Status(), node)
-				node.UpdateTree(decision)
+				if track.playout() {
+					start := time.Now()
+					backup(path, decision)
+					track.spent(updating, start)
+					track.offer(decision)
+				}
 				node.SetStatus(idle)
 			} else {
 				//log.Printf("updater: discarding %v node %p", node.Status(), node)
 			}
+			forget(path)
 		}
 	}
 }
 
 // A walker share the very same logic as UCT: it realizes selections and expansions of nodes.
 // It chooses moves to address the dilemma between exploration or exploitation.
-func walker(done <-chan struct{}, root *Node, position chan<- job) {
+// A walker retires instead of passing a node along.
+func walker(done <-chan struct{}, retire <-chan struct{}, rng *rand.Rand, root *Node, position chan<- job, track *tracker) {
 
 	for {
 		var score float64
 		var moves MoveSequence
 		var outch chan<- job = nil
 
+		track.trim()
+
 		node := root
+		path := []*Node{root}
 
+		track.enter()
+
+		start := time.Now()
 		for node.IsExpanded() {
-			node = node.Downselect()
+			next, oversampled := node.downselect(rng)
+			if oversampled {
+				track.oversampled()
+			}
 
-			move := node.Edge()
+			move := node.edgeTo(next)
 			moves = moves.Enqueue(move)
 			score += move.Score()
+
+			node = next
+			path = append(path, node)
 		}
 
-		if !node.IsTerminal() && node.Visits() > VisitThreshold {
-			node = node.ExpandOne(node.RandomNewEdge())
+		if !node.IsTerminal() && node.Visits() > node.conf.VisitThreshold && track.reserve() {
+			move := node.RandomNewEdge(rng)
+			parent := node
+			if node = node.ExpandOne(move); node.Up() == parent {
+				track.created()
+			} else {
+				track.release() // transposition
+			}
 
 			//log.Printf("walker: expanded %v node %p\n", node.Status(), node)
 
-			move := node.Edge()
 			moves = moves.Enqueue(move)
 			score += move.Score()
+
+			path = append(path, node)
 		}
+		track.reached(len(path) - 1)
+		track.spent(walking, start)
 
 		if node != nil {
 			outch = position // enable channel see https://golang.org/ref/spec#Channel_types
 			node.SetStatus(walked)
+			lose(path)
+			track.leave()
 		} else {
+			track.leave()
 			runtime.Gosched()
 			continue
 		}
 
 		select {
 		case <-done:
+			forget(path)
+			return
+		case <-retire:
+			node.SetStatus(idle)
+			forget(path)
 			return
-		case outch <- job{node, Decision{score: score, moves: moves}}:
+		case outch <- job{node, path, Decision{score: score, moves: moves}}:
 			// pass along if channel is enable (not nil), block on channel if necessary.
 			// from the spec: A nil channel is never ready for communication.
 		}
@@ -1,11 +1,112 @@
 package mcs
 
-import "testing"
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+	"time"
+)
 
 func TestCMCT(t *testing.T) {
+	game := newToy(4, 1, 3, 2)
 
+	root := NewRoot(game.Clone(), NewConfig(0.03, 40, 0))
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	result, _, _ := ConcurrentSearch(ctx, root, []GamePolicy{nil})
+	cancel()
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("cmct: small game not solved early (%v)", elapsed)
+	}
+
+	if !result.Solved() {
+		t.Fatalf("cmct: small game not solved")
+	}
+
+	if best := game.optimum(); result.Score() != best {
+		t.Errorf("cmct: expected proven optimum %g, got %g", best, result.Score())
+	}
+
+	score, err := replay(game, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if score != result.Score() {
+		t.Errorf("cmct: replayed %g, expected %g", score, result.Score())
+	}
 }
 
 func TestNCMCT(t *testing.T) {
 
 }
+
+// TestCMCT_race runs ConcurrentSearch in various configurations while the tree is
+// read from other goroutines. It is meant to be run with the race detector, eg.
+// go test -race -run CMCT_race
+func TestCMCT_race(t *testing.T) {
+	items := make([]int, 12)
+	for i := range items {
+		items[i] = i % 5
+	}
+	game := newToy(items...)
+
+	configs := []func(*Config){
+		func(conf *Config) {},
+		func(conf *Config) { conf.Transpositions = true },
+		func(conf *Config) { conf.Workers = Workers{Walkers: 4, Samplers: 4, Updaters: 4} },
+		func(conf *Config) { conf.Workers = Workers{Walkers: 2, Samplers: 3, Updaters: 2, Adaptive: true} },
+		func(conf *Config) { conf.VirtualLoss = VirtualLoss{Loss: 1} },
+		func(conf *Config) { conf.VirtualLoss = VirtualLoss{Loss: 0.1, Scaled: true} },
+		func(conf *Config) { conf.MaxNodes = 100 },
+		func(conf *Config) { conf.UCB = ADAUCB },
+	}
+
+	for i, configure := range configs {
+		conf := NewConfig(0.03, 40, 0)
+		conf.VisitThreshold = 0
+		configure(conf)
+
+		root := NewRoot(game.Clone(), conf)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+
+		// Readers walk the tree through its exported API while it's searched.
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for ctx.Err() == nil {
+				for _, node := range root.Down() {
+					node.Up()
+					node.Edge()
+					node.State()
+					node.Status()
+					node.Best()
+					node.IsExpanded()
+					for _, child := range node.Down() {
+						child.Visits()
+					}
+				}
+				WriteDOT(ioutil.Discard, root, Cut{Depth: 3})
+				RunningMetrics()
+			}
+		}()
+
+		result, _, err := ConcurrentSearch(ctx, root, []GamePolicy{nil})
+		cancel()
+		<-done
+
+		if err != nil {
+			t.Fatalf("cmct: config #%d, %v", i, err)
+		}
+
+		score, err := replay(game, result)
+		if err != nil {
+			t.Fatalf("cmct: config #%d, %v", i, err)
+		}
+		if score != result.Score() {
+			t.Errorf("cmct: config #%d, replayed %g, expected %g", i, score, result.Score())
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2018 Erik Adelbert. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mcs
+
+// Config gathers the settings of a search. It is given to NewRoot and shared by
+// all the nodes of the tree. It must not be modified once a search has started:
+// concurrent searches each use their own configuration.
+type Config struct {
+	UCB UCB // selection formula
+
+	Epsilon float64 // ε-greedy, initial entropy of nodes
+	C       float64 // exploration constant
+	W       float64 // weight of the best score
+
+	// VisitThreshold is the minimal number of simulations a position has to go
+	// through before being registered in the tree as a node.
+	VisitThreshold float64
+
+	// Transpositions merges identical positions into a DAG for games implementing
+	// the Hasher interface. It's off by default: a merged node mixes the scores of
+	// its paths, which are measured from the root, and is valued by the visits of
+	// its primary parent under every parent.
+	Transpositions bool
+
+	// Seed determines all the random choices of a search: a seed and a budget fully
+	// determine the result of a sequential search.
+	Seed int64
+
+	// Budget bounds the work of a search, there's no bound by default.
+	Budget Budget
+
+	// Workers sizes the pipeline of ConcurrentSearch, defaults are used when zero.
+	// Workers are not saved with the tree: they depend on the machine.
+	Workers Workers
+
+	// VirtualLoss spreads the walkers of ConcurrentSearch, there's none by default.
+	// It's not saved with the tree either.
+	VirtualLoss VirtualLoss
+
+	// MaxNodes caps the size of the tree, there's no cap when zero. Once the cap
+	// is reached, low-visit subtrees are pruned, see Stats.Pruned. Subtrees being
+	// searched are spared and the walkers of ConcurrentSearch wait while the tree
+	// is pruned, virtual loss or not. The cap isn't saved with the tree: it
+	// depends on the memory of the machine.
+	MaxNodes int
+
+	// Progress, when set, receives every improvement of the best decision during
+	// a search. It is called from the search goroutines, one call at a time, and
+	// should return quickly.
+	Progress func(Progress)
+}
+
+// NewConfig returns a configuration using UCBTunedSinglePlayer and the default
+// visit threshold.
+func NewConfig(ε, c, w float64) *Config {
+	return &Config{
+		UCB: UCBTunedSinglePlayer,
+
+		Epsilon: ε,
+		C:       c,
+		W:       w,
+
+		VisitThreshold: VisitThreshold,
+	}
+}
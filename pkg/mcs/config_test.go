@@ -0,0 +1,57 @@
+package mcs
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewConfig(t *testing.T) {
+	conf := NewConfig(0.03, 40, 0.2)
+
+	if conf.UCB == nil {
+		t.Errorf("config: no default formula")
+	}
+
+	if conf.VisitThreshold != VisitThreshold {
+		t.Errorf("config: expected visit threshold %d, got %g", VisitThreshold, conf.VisitThreshold)
+	}
+}
+
+// Two searches with different formulas run side by side.
+func TestConfig_concurrent(t *testing.T) {
+	var calls [2]int64
+
+	counted := func(i int, ucb UCB) UCB {
+		return func(n *Node) float64 {
+			atomic.AddInt64(&calls[i], 1)
+			return ucb(n)
+		}
+	}
+
+	confs := []*Config{NewConfig(0.03, 40, 0), NewConfig(0.03, 1, 0)}
+	confs[0].UCB = counted(0, UCB1)
+	confs[1].UCB = counted(1, UCBV)
+
+	var wg sync.WaitGroup
+	for _, conf := range confs {
+		wg.Add(1)
+		go func(conf *Config) {
+			defer wg.Done()
+
+			root := NewRoot(newToy(1, 2, 3, 4, 5, 6), conf)
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			ConfidentSearch(ctx, root, []GamePolicy{nil})
+			cancel()
+		}(conf)
+	}
+	wg.Wait()
+
+	for i := range calls {
+		if atomic.LoadInt64(&calls[i]) == 0 {
+			t.Errorf("search #%d: formula never called", i)
+		}
+	}
+}
@@ -17,7 +17,7 @@ import (
 type Decision struct {
 	moves  MoveSequence
 	score  float64
-	solved float64 // not used yet
+	solved bool // the score is proven optimal
 }
 
 // Clone returns an independent copy of a decision.
@@ -27,6 +27,7 @@ func (d Decision) Clone() Decision {
 	clone.moves = make(MoveSequence, d.Moves().Len())
 	copy(clone.moves, d.Moves())
 	clone.score = d.Score()
+	clone.solved = d.Solved()
 	return clone
 }
 
@@ -47,37 +48,42 @@ func (d Decision) Score() float64 {
 	return d.score
 }
 
-// Solved is not yet functional.
-func (d Decision) Solved() float64 {
+// Solved is true when the decision has been proven optimal by the search.
+func (d Decision) Solved() bool {
 	return d.solved
 }
 
 // SetMoves is a setter.
-func (d Decision) SetMoves(m MoveSequence) {
+func (d *Decision) SetMoves(m MoveSequence) {
 	d.moves = m
 }
 
 // SetScore is a setter.
-func (d Decision) SetScore(score float64) {
+func (d *Decision) SetScore(score float64) {
 	d.score = score
 }
 
-// SetSolved isn't functional yet.
-func (d Decision) SetSolved(solved float64) {
+// SetSolved is a setter.
+func (d *Decision) SetSolved(solved bool) {
 	d.solved = solved
 }
 
+// decide concludes a search: a solved root yields its proof, the best decision
+// found so far otherwise.
+func decide(root *Node) Decision {
+	if root.IsSolved() {
+		return root.Proof()
+	}
+	return root.Best()
+}
+
 func (d Decision) String() string {
-	var sb strings.Builder
+	var sb strings.Builder // writes never fail
 
-	if _, err := fmt.Fprintf(&sb, "score: %g, solved: %v\n", d.score, d.solved); err != nil {
-		panic(err)
-	}
+	fmt.Fprintf(&sb, "score: %g, solved: %v\n", d.score, d.solved)
 
 	for i := 0; i < len(d.moves); i++ {
-		if _, err := fmt.Fprintf(&sb, "%2d: %s\t", i, d.moves[i].String()); err != nil {
-			panic(err)
-		}
+		fmt.Fprintf(&sb, "%2d: %s\t", i, d.moves[i].String())
 
 		if (i+1)%6 == 0 {
 			sb.WriteByte('\n')
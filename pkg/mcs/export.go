@@ -0,0 +1,205 @@
+// Copyright 2018 Erik Adelbert. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements trees exports to Graphviz (DOT) and JSON. Exports are meant
+// to compare trees shapes between searches and to illustrate papers.
+// see https://graphviz.org/doc/info/lang.html
+
+package mcs
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Cut bounds an export: nodes deeper than Depth below the exported node or with
+// less than Visits simulations are left out, along with their descendants. Zero
+// values stand for no limit.
+type Cut struct {
+	Depth  int
+	Visits float64
+}
+
+// WriteDOT exports the tree grown from root as a Graphviz digraph. Solved nodes
+// are filled, transpositions are drawn with dashed arcs.
+func WriteDOT(w io.Writer, root *Node, cut Cut) error {
+	if root == nil {
+		return ErrNilRoot
+	}
+
+	nodes, arcs := export(root, cut)
+
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintln(out, "digraph tree {")
+	fmt.Fprintln(out, "\tnode [shape=box, fontname=monospace];")
+
+	for _, n := range nodes {
+		edge := n.Edge
+		if edge == "" {
+			edge = "root"
+		}
+
+		label := fmt.Sprintf("%s\\nvisits: %g\\nmean: %g\\nvariance: %g\\nucb: %g\\nbest: %g\\n%s",
+			escape(edge), n.Visits, n.Mean, n.Variance, n.UCB, n.Best, n.Status)
+
+		style := ""
+		if n.Solved {
+			label += fmt.Sprintf(", solved: %g", n.Exact)
+			style = ", style=filled, fillcolor=lightgrey"
+		}
+
+		fmt.Fprintf(out, "\tn%d [label=\"%s\"%s];\n", n.ID, label, style)
+	}
+
+	for _, a := range arcs {
+		style := ""
+		if a.Transposed {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(out, "\tn%d -> n%d [label=\"%s\"%s];\n", a.From, a.To, escape(a.Move), style)
+	}
+
+	fmt.Fprintln(out, "}")
+
+	return out.Flush()
+}
+
+// WriteJSON exports the tree grown from root as lists of nodes and arcs. Undefined
+// values (eg. the variance of unvisited nodes or the exact value of unsolved nodes)
+// and infinite ones are null.
+func WriteJSON(w io.Writer, root *Node, cut Cut) error {
+	if root == nil {
+		return ErrNilRoot
+	}
+
+	nodes, arcs := export(root, cut)
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+
+	return enc.Encode(struct {
+		Nodes []exported `json:"nodes"`
+		Arcs  []arc      `json:"arcs"`
+	}{nodes, arcs})
+}
+
+// exported is the state of a node at the time of an export.
+type exported struct {
+	ID     int    `json:"id"`
+	Depth  int    `json:"depth"`
+	Edge   string `json:"edge"`
+	Status string `json:"status"`
+
+	Visits   number `json:"visits"`
+	Mean     number `json:"mean"`
+	Variance number `json:"variance"`
+	UCB      number `json:"ucb"`
+	Best     number `json:"best"`
+
+	Solved bool   `json:"solved"`
+	Exact  number `json:"exact"`
+}
+
+// arc links a node to one of its children.
+type arc struct {
+	From       int    `json:"from"`
+	To         int    `json:"to"`
+	Move       string `json:"move"`
+	Transposed bool   `json:"transposed,omitempty"`
+}
+
+// number is a float64 whose undefined and infinite values are encoded as null.
+type number float64
+
+func (x number) MarshalJSON() ([]byte, error) {
+	if f := float64(x); math.IsNaN(f) || math.IsInf(f, 0) {
+		return []byte("null"), nil
+	}
+	return strconv.AppendFloat(nil, float64(x), 'g', -1, 64), nil
+}
+
+// export lists the nodes of the tree grown from root in breadth first order,
+// within the cut, and the arcs between them. A node's edge is the move from its
+// primary parent, other arcs are transpositions.
+func export(root *Node, cut Cut) ([]exported, []arc) {
+	var nodes []exported
+	var arcs []arc
+
+	ids := map[*Node]int{root: 0}
+	queue := []*Node{root}
+
+	for i := 0; i < len(queue); i++ {
+		node := queue[i]
+
+		var down []*Node
+		var n exported
+
+		node.Lock()
+		{
+			n = exported{
+				ID:     i,
+				Depth:  node.depth,
+				Status: NodeStatus(atomic.LoadInt32(&node.status)).String(),
+
+				Visits:   number(node.visits.load()),
+				Mean:     number(node.mean.load()),
+				Variance: number(node.variance.load() / node.visits.load()),
+				UCB:      number(node.value.load()),
+				Best:     number(node.best.score),
+
+				Solved: node.proven,
+				Exact:  number(math.NaN()),
+			}
+
+			if node.edge != nil {
+				n.Edge = node.edge.String()
+			}
+
+			if node.proven {
+				n.Exact = number(node.exact)
+			}
+
+			down = append(down, node.down...)
+		}
+		node.Unlock()
+
+		nodes = append(nodes, n)
+
+		for _, child := range down {
+			if cut.Depth > 0 && child.Depth()-root.Depth() > cut.Depth {
+				continue
+			}
+
+			if cut.Visits > 0 && child.Visits() < cut.Visits {
+				continue
+			}
+
+			id, ok := ids[child]
+			if !ok {
+				id = len(queue)
+				ids[child] = id
+				queue = append(queue, child)
+			}
+
+			arcs = append(arcs, arc{
+				From:       i,
+				To:         id,
+				Move:       node.edgeTo(child).String(),
+				Transposed: child.Up() != node,
+			})
+		}
+	}
+
+	return nodes, arcs
+}
+
+// escape quotes a DOT string.
+var escape = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace
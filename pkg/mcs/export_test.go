@@ -0,0 +1,87 @@
+package mcs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteJSON(t *testing.T) {
+	game := newToy(4, 1, 3, 2)
+
+	conf := NewConfig(0.03, 40, 0)
+	conf.Transpositions = true
+
+	root := NewRoot(game.Clone(), conf)
+	expand(root, map[*Node]bool{})
+
+	cases := []struct {
+		cut         Cut
+		nodes, arcs int
+	}{
+		{Cut{}, 16, 4 + 4*3 + 6*2 + 4*1},
+		{Cut{Depth: 1}, 5, 4},
+		{Cut{Visits: 1}, 1, 0}, // no simulation yet
+	}
+
+	for _, tc := range cases {
+		var out bytes.Buffer
+		if err := WriteJSON(&out, root, tc.cut); err != nil {
+			t.Fatal(err)
+		}
+
+		var tree struct {
+			Nodes []struct {
+				Edge     string
+				Visits   float64
+				Variance *float64
+				Solved   bool
+			}
+			Arcs []struct {
+				From, To   int
+				Transposed bool
+			}
+		}
+
+		if err := json.Unmarshal(out.Bytes(), &tree); err != nil {
+			t.Fatalf("json: %v\n%s", err, out.String())
+		}
+
+		if len(tree.Nodes) != tc.nodes || len(tree.Arcs) != tc.arcs {
+			t.Errorf("json: cut %+v, expected %d nodes and %d arcs, got %d and %d",
+				tc.cut, tc.nodes, tc.arcs, len(tree.Nodes), len(tree.Arcs))
+		}
+
+		if root := tree.Nodes[0]; !root.Solved || root.Variance != nil || root.Edge != "" {
+			t.Errorf("json: unexpected root %+v", root)
+		}
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	game := newToy(4, 1, 3, 2)
+
+	conf := NewConfig(0.03, 40, 0)
+	conf.Budget = Budget{Playouts: 100}
+
+	root := NewRoot(game.Clone(), conf)
+	ConfidentSearch(context.Background(), root, []GamePolicy{nil})
+
+	var out bytes.Buffer
+	if err := WriteDOT(&out, root, Cut{Depth: 2}); err != nil {
+		t.Fatal(err)
+	}
+	dot := out.String()
+
+	if !strings.HasPrefix(dot, "digraph tree {") || !strings.HasSuffix(dot, "}\n") {
+		t.Errorf("dot: not a digraph\n%s", dot)
+	}
+
+	for _, want := range []string{"n0 [label=\"root\\nvisits: 100", "n0 -> n1", "ucb: ", "best: "} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("dot: %q not found\n%s", want, dot)
+		}
+	}
+}
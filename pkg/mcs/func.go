@@ -5,13 +5,40 @@
 package mcs
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"runtime"
-	"time"
 )
 
-// Search is a function that implements a Monte-Carlo technique.
-type Search func(*Node, []GamePolicy, time.Duration) Decision
+// Search is a function that implements a Monte-Carlo technique. A search runs
+// until its context is done, either by deadline or cancellation, then it returns
+// the best decision found so far along with the statistics of its work. An error
+// is returned when there's nothing to search, see check.
+type Search func(context.Context, *Node, []GamePolicy) (Decision, Stats, error)
+
+// Errors returned by searches. ErrNilRoot is also returned when saving, exporting
+// or loading a tree without a root or an initial position.
+var (
+	ErrNilRoot  = errors.New("mcs: nil root")
+	ErrNoPolicy = errors.New("mcs: no game policy")
+	ErrNoMoves  = errors.New("mcs: no legal moves from the root")
+)
+
+// check validates the arguments of a search. A terminal root isn't searched: its
+// decision, which is the final score of the game, comes along with ErrNoMoves.
+func check(root *Node, policies []GamePolicy) (Decision, error) {
+	switch {
+	case root == nil:
+		return Decision{}, ErrNilRoot
+	case len(policies) == 0:
+		return Decision{}, ErrNoPolicy
+	case root.IsTerminal():
+		return decide(root), ErrNoMoves
+	}
+
+	return Decision{}, nil
+}
 
 func (s Search) String() string {
 	return runtime.FuncForPC(reflect.ValueOf(s).Pointer()).Name()
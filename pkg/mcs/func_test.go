@@ -0,0 +1,87 @@
+package mcs
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSearch_cancel(t *testing.T) {
+	items := make([]int, 30)
+	for i := range items {
+		items[i] = i
+	}
+	game := newToy(items...)
+
+	searches := []Search{
+		ConcurrentSearch,
+		ConfidentSearch,
+		MetaSearch,
+		NestedSearch,
+		AdaptiveSearch,
+	}
+
+	for _, search := range searches {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			cancel()
+		}()
+
+		root := NewRoot(game.Clone(), NewConfig(0.03, 40, 0))
+
+		start := time.Now()
+		result, _, _ := search(ctx, root, []GamePolicy{nil})
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%v: cancelled late (%v)", search, elapsed)
+		}
+
+		score, err := replay(game, result)
+		if err != nil {
+			t.Errorf("%v: %v", search, err)
+			continue
+		}
+
+		if score != result.Score() {
+			t.Errorf("%v: replayed %g, expected %g", search, score, result.Score())
+		}
+	}
+}
+
+func TestSearch_errors(t *testing.T) {
+	searches := []Search{
+		ConcurrentSearch,
+		ConfidentSearch,
+		MetaSearch,
+		NestedSearch,
+		AdaptiveSearch,
+		RootParallelSearch,
+		LeafParallelSearch,
+		OnlineSearch(ConfidentSearch, 0),
+	}
+
+	for _, search := range searches {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+
+		if _, _, err := search(ctx, nil, []GamePolicy{nil}); err != ErrNilRoot {
+			t.Errorf("%v: nil root, expected %v, got %v", search, ErrNilRoot, err)
+		}
+
+		root := NewRoot(newToy(1, 2).Clone(), NewConfig(0.03, 40, 0))
+		if _, _, err := search(ctx, root, nil); err != ErrNoPolicy {
+			t.Errorf("%v: no policy, expected %v, got %v", search, ErrNoPolicy, err)
+		}
+
+		// A finished game is decided anyway.
+		root = NewRoot(newToy().Clone(), NewConfig(0.03, 40, 0))
+		result, _, err := search(ctx, root, []GamePolicy{nil})
+		if err != ErrNoMoves {
+			t.Errorf("%v: finished game, expected %v, got %v", search, ErrNoMoves, err)
+		}
+		if !result.Solved() || result.Moves().Len() != 0 || result.Score() != 0 {
+			t.Errorf("%v: finished game, expected solved 0, got %v", search, result)
+		}
+
+		cancel()
+	}
+}
@@ -0,0 +1,167 @@
+package mcs
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+)
+
+// toy is a tiny single player game used to test searches: items are taken one
+// at a time and each item scores its value times the turn it is taken at.
+// By the rearrangement inequality, the best sequence takes items in ascending
+// order. Different orders reach the same positions, they are identified by Hash.
+type toy struct {
+	items []int
+	turn  int
+}
+
+type toyMove struct {
+	item, turn int
+}
+
+type toyHand []Move
+
+func newToy(items ...int) toy {
+	sorted := append([]int(nil), items...)
+	sort.Ints(sorted)
+	return toy{items: sorted, turn: 1}
+}
+
+// optimum is the score of the best sequence.
+func (t toy) optimum() float64 {
+	score := 0.0
+	for i, item := range t.items {
+		score += float64(item * (t.turn + i))
+	}
+	return score
+}
+
+func (t toy) Clone() GameState {
+	return toy{items: append([]int(nil), t.items...), turn: t.turn}
+}
+
+func (t toy) Hash() uint64 {
+	h := fnv.New64a()
+	fmt.Fprint(h, t.items, t.turn)
+	return h.Sum64()
+}
+
+func (t toy) Moves() MoveSet {
+	hand := make(toyHand, 0, len(t.items))
+	for i, item := range t.items {
+		if i > 0 && item == t.items[i-1] {
+			continue
+		}
+		hand = append(hand, toyMove{item, t.turn})
+	}
+	return hand
+}
+
+func (t toy) Play(m Move) GameState {
+	move := m.(toyMove)
+	for i, item := range t.items {
+		if item == move.item {
+			t.items = append(t.items[:i:i], t.items[i+1:]...)
+			break
+		}
+	}
+	t.turn++
+	return t
+}
+
+func (t toy) Sample(ctx context.Context, rng *rand.Rand, policy GamePolicy) (float64, MoveSequence) {
+	var state GameState = t
+	var moves MoveSequence
+	var score float64
+
+	for hand := state.Moves(); hand.Len() > 0; hand = state.Moves() {
+		select {
+		case <-ctx.Done():
+			return score, moves
+		default:
+		}
+
+		list := hand.List()
+
+		var move Move
+		if w, ok := policy.(Weights); ok {
+			codes := make([]uint64, len(list))
+			for i, m := range list {
+				codes[i] = Code(state, m)
+			}
+			move = list[w.Pick(rng, codes)]
+		} else {
+			move = list[rng.Intn(len(list))]
+		}
+		state = state.Play(move)
+		moves = moves.Enqueue(move)
+		score += move.Score()
+	}
+
+	return score, moves
+}
+
+func (t toy) Score() float64 {
+	return 0
+}
+
+func (t toy) String() string {
+	return fmt.Sprint(t.items, "@", t.turn)
+}
+
+func (m toyMove) Len() int {
+	return 1
+}
+
+func (m toyMove) Score() float64 {
+	return float64(m.item * m.turn)
+}
+
+func (m toyMove) String() string {
+	return fmt.Sprintf("%d@%d", m.item, m.turn)
+}
+
+func (h toyHand) Draw(rng *rand.Rand) (Move, MoveSet) {
+	if len(h) == 0 {
+		return nil, h
+	}
+	i := rng.Intn(len(h))
+	move := h[i]
+	h[i] = h[len(h)-1]
+	return move, h[:len(h)-1]
+}
+
+func (h toyHand) Len() int {
+	return len(h)
+}
+
+func (h toyHand) List() []Move {
+	return h
+}
+
+// replay checks a decision against the game rules and returns its actual score.
+func replay(initial GameState, d Decision) (float64, error) {
+	state := initial.Clone()
+	score := 0.0
+
+	for _, move := range d.Moves() {
+		legal := false
+		for _, m := range state.Moves().List() {
+			legal = legal || m.String() == move.String()
+		}
+		if !legal {
+			return 0, fmt.Errorf("illegal move %v in %v", move, state)
+		}
+
+		score += move.Score()
+		state = state.Play(move)
+	}
+
+	if state.Moves().Len() > 0 {
+		return 0, fmt.Errorf("unfinished game %v", state)
+	}
+
+	return score + state.Score(), nil
+}
@@ -2,47 +2,40 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package mcs
+// This file defines the contract a game has to fulfill in order to be searched.
+// Games are plugged in through adapters (see games/samegame and games/clickomania).
 
-import "mcs/games/samegame"
+package mcs
 
-// (╯°□°）╯︵ ┻━┻ poor mans's generic:
+import (
+	"context"
+	"math/rand"
+)
 
 // GameState can be anything that describes accurately the state of a game.
 // In samegame it's a board.
-type GameState samegame.State
-
-// Clone returns a memory-independent copy.
-func (g GameState) Clone() GameState {
-	return GameState(samegame.State(g).Clone())
-}
-
-// Moves returns a list of legal moves from the calling state.
-func (g GameState) Moves() MoveSet {
-	return MoveSet(samegame.State(g).Moves())
-}
+type GameState interface {
+	// Clone returns a memory-independent copy.
+	Clone() GameState
 
-// Play returns the game state after the given move has been played in the
-// calling state.
-func (g GameState) Play(m Move) GameState {
-	return GameState(samegame.State(g).Play(m.(samegame.Move)))
-}
+	// Moves returns a list of legal moves from the calling state.
+	Moves() MoveSet
 
-// Sample simulates a game to its end by applying a move selection policy. The policy usually
-// embeds randomness.
-func (g GameState) Sample(done <-chan struct{}, policy GamePolicy) Decision {
-	score, moves := samegame.State(g).Sample(done, samegame.ColorPolicy(policy))
+	// Play returns the game state after the given move has been played in the
+	// calling state. The calling state may be modified in the process.
+	Play(Move) GameState
 
-	return Decision{moves: MoveSequence(moves), score: score}
-}
+	// Sample simulates a game to its end by applying a move selection policy.
+	// The policy usually embeds randomness. It returns the score of the simulated
+	// moves including the final score of the ending position. Sampling is expected
+	// to stop early when the context is done. All random choices are to be drawn
+	// from the given generator for searches to be reproducible.
+	Sample(context.Context, *rand.Rand, GamePolicy) (float64, MoveSequence)
 
-// Score returns a statically computed score of the calling state.
-func (g GameState) Score() float64 {
-	return samegame.State(g).Score()
-}
+	// Score returns a statically computed score of the calling state.
+	Score() float64
 
-func (g GameState) String() string {
-	return samegame.State(g).String()
+	String() string
 }
 
 // Move has a length, is scorable and printable.
@@ -52,90 +45,98 @@ type Move interface {
 	String() string
 }
 
+// MoveSet is a collection of legal moves.
+type MoveSet interface {
+	// Draw randomly removes a move from the set.
+	Draw(*rand.Rand) (Move, MoveSet)
+
+	// Len returns the number of legal moves.
+	Len() int
+
+	// List returns all the moves present in the set.
+	List() []Move
+}
+
+// GamePolicy is a game policy used during the simulation step.
+// It is a reference passed back to the game sampler.
+type GamePolicy interface{}
+
 // MoveSequence is a FIFO structure.
-type MoveSequence samegame.Sequence
+type MoveSequence []Move
 
 // Clone returns an independent copy of the calling sequence.
 func (s MoveSequence) Clone() MoveSequence {
-	return MoveSequence(samegame.Sequence(s).Clone())
+	clone := make(MoveSequence, len(s))
+	copy(clone, s)
+	return clone
 }
 
 // Dequeue is customary for FIFO structures.
 func (s MoveSequence) Dequeue() (Move, MoveSequence) {
-	move, seq := samegame.Sequence(s).Dequeue()
-	return Move(move), MoveSequence(seq)
+	return s[0], s[1:]
 }
 
-// Enqueue is customary for FIFO structures.
+// Enqueue is customary for FIFO structures. Empty moves are discarded.
 func (s MoveSequence) Enqueue(m Move) MoveSequence {
-	return MoveSequence(samegame.Sequence(s).Enqueue(m.(samegame.Move)))
+	if m == nil || m.Len() == 0 {
+		return s
+	}
+	return append(s, m)
 }
 
 // Join returns an aggregated sequence.
 func (s MoveSequence) Join(t MoveSequence) MoveSequence {
-	return MoveSequence(samegame.Sequence(s).Join(samegame.Sequence(t)))
+	return append(s, t...)
 }
 
 // Len returns the number of moves in the sequence.
 func (s MoveSequence) Len() int {
-	return samegame.Sequence(s).Len()
+	return len(s)
 }
 
-// MoveSet is a collection of legal moves.
-type MoveSet samegame.Hand
+// noMoves is an empty set of moves. It stands for the hand of a fully
+// expanded node.
+var noMoves MoveSet = emptySet{}
+
+type emptySet struct{}
 
-// Draw randomly removes a move from the set.
-func (m MoveSet) Draw() (Move, MoveSet) {
-	move, set := samegame.Hand(m).Draw()
-	return Move(move), MoveSet(set)
+func (e emptySet) Draw(rng *rand.Rand) (Move, MoveSet) {
+	return nil, e
 }
 
-// Len returns the number of legal moves.
-func (m MoveSet) Len() int {
-	return samegame.Hand(m).Len()
+func (e emptySet) Len() int {
+	return 0
 }
 
-// List returns all the moves present in the set.
-func (m MoveSet) List() []Move {
-	list := samegame.Hand(m).List()
+func (e emptySet) List() []Move {
+	return nil
+}
+
+// moveList is a plain set of moves, it stands for the partial hand of a loaded node.
+type moveList []Move
 
-	moves := make([]Move, 0, len(list))
-	for _, move := range list {
-		moves = append(moves, Move(move))
+func (l moveList) Draw(rng *rand.Rand) (Move, MoveSet) {
+	if len(l) == 0 {
+		return nil, l
 	}
-	return moves
-}
 
-// GamePolicy is a game policy used during the simulation step.
-// It is a reference passed back to the game sampler.
-type GamePolicy samegame.ColorPolicy
+	i, last := rng.Intn(len(l)), len(l)-1
+	move := l[i]
+	l[i], l[last] = l[last], nil
 
-// (╯°□°）╯︵ ┻━┻ here is precisely what interfaces aren't for:
-/*
-type GameState interface {
-	Clone() GameState
-	Moves() MoveSet
-	Play(Edge) GameState
-	Sample(<-chan struct{}, float64, GamePolicy) (float64, MoveSequence)
-	Score() float64
-	String() string
+	return move, l[:last]
 }
 
-type Edge interface {
-	Score() float64
+func (l moveList) Len() int {
+	return len(l)
 }
 
-type MoveSequence interface {
-	Enqueue(Edge) MoveSequence
-	Join(MoveSequence) MoveSequence
-	Len() int
+func (l moveList) List() []Move {
+	return l
 }
 
-type MoveSet interface {
-	Draw() (Edge, MoveSet)
-	Len() int
-	List() []Edge
+// simulate plays a game from the given state and records the outcome as a decision.
+func simulate(ctx context.Context, rng *rand.Rand, g GameState, policy GamePolicy) Decision {
+	score, moves := g.Sample(ctx, rng, policy)
+	return Decision{moves: moves, score: score}
 }
-
-type GamePolicy interface{}
-*/
@@ -0,0 +1,121 @@
+// Copyright 2018 Erik Adelbert. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements virtual loss for tree parallelization.
+
+package mcs
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+)
+
+// VirtualLoss spreads the walkers of ConcurrentSearch over the tree. Every node on
+// the path of a simulation in progress is valued as if it had been visited Loss
+// more times with the lowest score seen through its parent, until the outcome is
+// back propagated. The value is computed anew by the UCB formula: the loss is
+// applied in the scale of the formula and never raises the value. Parents
+// of busy nodes are then less likely to be selected again but, unlike without
+// virtual loss, they are not excluded from selection.
+// see https://dke.maastrichtuniversity.nl/m.winands/documents/multithreadedMCTS2.pdf
+type VirtualLoss struct {
+	Loss float64 // virtual visits per simulation in progress, none when zero
+
+	// Scaled multiplies the loss by the visits of the node: a constant loss fades
+	// away as nodes are visited while a scaled loss keeps the same weight.
+	Scaled bool
+}
+
+func (vl VirtualLoss) String() string {
+	switch {
+	case vl.Loss <= 0:
+		return "no virtual loss"
+	case vl.Scaled:
+		return fmt.Sprintf("virtual loss %g×visits", vl.Loss)
+	default:
+		return fmt.Sprintf("virtual loss %g", vl.Loss)
+	}
+}
+
+// lose applies a virtual loss to the nodes of a path, the root excepted.
+func lose(path []*Node) {
+	if len(path) == 0 || path[0].conf.VirtualLoss.Loss <= 0 {
+		return
+	}
+
+	for _, node := range path[1:] {
+		atomic.AddInt32(&node.pending, 1)
+		node.devalue()
+		node.rerank()
+	}
+}
+
+// forget removes the virtual loss applied to a path once its outcome is back
+// propagated or given up.
+func forget(path []*Node) {
+	if len(path) == 0 || path[0].conf.VirtualLoss.Loss <= 0 {
+		return
+	}
+
+	for _, node := range path[1:] {
+		atomic.AddInt32(&node.pending, -1)
+		node.devalue()
+		node.rerank()
+	}
+}
+
+// virtualValue returns the value of the calling node lowered by the virtual loss
+// of the simulations in progress through it, see devalue. The value of unvisited
+// nodes is left unchanged.
+func (n *Node) virtualValue() float64 {
+	value := n.value.load()
+	if atomic.LoadInt32(&n.pending) == 0 || n.conf.VirtualLoss.Loss <= 0 || n.visits.load() == 0 {
+		return value
+	}
+
+	return math.Min(value, n.lowered.load())
+}
+
+// devalue evaluates the calling node anew under the virtual loss of its pending
+// simulations: they count as visits at the lowest score seen through the parent,
+// and the UCB formula is applied to the statistics thus lowered. It is called once
+// the value or the pending simulations change, the node must not be locked.
+func (n *Node) devalue() {
+	vl := n.conf.VirtualLoss
+	pending := atomic.LoadInt32(&n.pending)
+	if pending == 0 || vl.Loss <= 0 {
+		return
+	}
+
+	s := n.statistics()
+	if s.visits == 0 {
+		return
+	}
+
+	losses := float64(pending) * vl.Loss
+	if vl.Scaled {
+		losses *= s.visits
+	}
+
+	up := n.Up()
+	worst := s.worst
+	if up != nil {
+		worst = math.Min(worst, up.Worst())
+	}
+
+	// The formula reads the statistics of a stand-in node. The spread of actual
+	// outcomes is kept: a variance grown by the losses would raise the bonus of
+	// variance aware formulas.
+	visits := s.visits + losses
+
+	lowered := &Node{spinlock: newSpinlock(), up: up, conf: n.conf}
+	lowered.visits.store(visits)
+	lowered.mean.store(s.mean + (worst-s.mean)*losses/visits)
+	lowered.variance.store(s.variance)
+	lowered.worst.store(worst)
+	lowered.top.store(s.best)
+
+	n.lowered.store(n.conf.UCB(lowered))
+}
@@ -0,0 +1,115 @@
+package mcs
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestVirtualLoss(t *testing.T) {
+	game := newToy(6, 1, 5, 2, 4, 3)
+
+	for _, vl := range []VirtualLoss{{Loss: 3}, {Loss: 0.5, Scaled: true}} {
+		conf := NewConfig(0.03, 40, 0)
+		conf.VirtualLoss = vl
+
+		root := NewRoot(game.Clone(), conf)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		result, _, _ := ConcurrentSearch(ctx, root, []GamePolicy{nil})
+		cancel()
+
+		if !result.Solved() {
+			t.Fatalf("cmct: %v, small game not solved", vl)
+		}
+
+		if best := game.optimum(); result.Score() != best {
+			t.Errorf("cmct: %v, expected proven optimum %g, got %g", vl, best, result.Score())
+		}
+
+		// Every virtual loss has been taken back.
+		for _, node := range root.subtree() {
+			if node.pending != 0 {
+				t.Errorf("cmct: %v, %d simulations left pending", vl, node.pending)
+				break
+			}
+		}
+	}
+}
+
+func TestNode_virtualValue(t *testing.T) {
+	tests := []struct {
+		vl      VirtualLoss
+		visits  float64
+		pending int32
+		value   float64
+	}{
+		{VirtualLoss{Loss: 1}, 4, 0, 10},
+		{VirtualLoss{Loss: 1}, 4, 1, 8},                   // (10×4 + 0×1) / 5
+		{VirtualLoss{Loss: 1}, 9, 1, 9},                   // (10×9 + 0×1) / 10
+		{VirtualLoss{Loss: 0.25, Scaled: true}, 4, 1, 8},  // (10×4 + 0×1) / 5
+		{VirtualLoss{Loss: 0.25, Scaled: true}, 16, 1, 8}, // (10×16 + 0×4) / 20
+		{VirtualLoss{Loss: 1}, 0, 1, 10},                  // unvisited
+		{VirtualLoss{}, 4, 1, 10},
+	}
+
+	for _, test := range tests {
+		conf := NewConfig(0, 0, 0)
+		conf.UCB = UCB1 // its value is the mean without exploration
+		conf.VirtualLoss = test.vl
+
+		node := lossNode(conf, test.visits, 10, 0, 0)
+		node.pending = test.pending
+		node.Evaluate()
+		if test.visits == 0 {
+			node.SetValue(10)
+		}
+
+		if got := node.virtualValue(); got != test.value {
+			t.Errorf("virtual loss: %v, %g visits, %d pending, expected %g, got %g",
+				test.vl, test.visits, test.pending, test.value, got)
+		}
+	}
+
+	// Losses are applied in the scale of the formula: scores may lie far away from
+	// values in [0,1], the value is lowered anyway and never raised.
+	for _, ucb := range []UCB{UCB1, UCBTunedSinglePlayer, UCBV, ADAUCB, KLUCB} {
+		for _, worst := range []float64{-1000, 50} {
+			conf := NewConfig(0.03, 40, 0)
+			conf.UCB = ucb
+			conf.VirtualLoss = VirtualLoss{Loss: 3}
+
+			node := lossNode(conf, 4, 60, math.Min(worst, 60), 100)
+			value := node.Evaluate()
+
+			atomic.AddInt32(&node.pending, 1)
+			node.devalue()
+
+			if got := node.virtualValue(); !(got < value) {
+				t.Errorf("virtual loss: %v, worst %g, value %g not lowered, %g", ucb, worst, value, got)
+			}
+		}
+	}
+}
+
+// lossNode returns a visited child of a visited root whose lowest score is worst.
+func lossNode(conf *Config, visits, mean, worst, top float64) *Node {
+	root := NewRoot(newToy(1, 2), conf)
+	node := root.ExpandOne(root.Hand().List()[0])
+	root.ExpandOne(root.Hand().List()[1])
+
+	root.visits.store(visits + 1)
+	root.mean.store(mean)
+	root.worst.store(worst)
+	root.top.store(top)
+
+	node.visits.store(visits)
+	node.mean.store(mean)
+	node.variance.store(visits)
+	node.worst.store(worst)
+	node.top.store(top)
+
+	return node
+}
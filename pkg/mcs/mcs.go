@@ -6,4 +6,7 @@
 // Right now, only single player puzzles are supported. A new Monte-Carlo
 // search, SP-CMCT, is presented. SP-UCT is implemented, Samegame and Clickomania
 // are given as examples.
+//
+// Searches are game agnostic: a game is plugged in by implementing the GameState,
+// MoveSet and Move interfaces (see games/samegame/adapter.go).
 package mcs
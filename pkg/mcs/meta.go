@@ -5,6 +5,7 @@
 package mcs
 
 import (
+	"context"
 	"log"
 	"time"
 )
@@ -12,10 +13,24 @@ import (
 const slot = 10 * time.Minute
 
 // MetaSearch splits allowed thinking time into time slots. A new search is launched
-// for each time slot (cycle) and the best result is returned.
-func MetaSearch(root *Node, policies []GamePolicy, duration time.Duration) Decision {
+// for each time slot (cycle) and the best result is returned. Thinking time runs up
+// to the deadline of ctx, without deadline a single search is launched. Progress
+// is reported cycle by cycle and statistics are summed up over cycles.
+func MetaSearch(ctx context.Context, root *Node, policies []GamePolicy) (Decision, Stats, error) {
+	if decision, err := check(root, policies); err != nil {
+		return decision, Stats{}, err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ConcurrentSearch(ctx, root, policies)
+	}
+	duration := time.Until(deadline)
+
 	var best Decision
+	var stats, cycled Stats
 	var cycle time.Duration
+	var err error
 
 	switch {
 	case duration > 3*slot: // from 31mn to 50mn: 2 runs
@@ -27,19 +42,47 @@ func MetaSearch(root *Node, policies []GamePolicy, duration time.Duration) Decis
 	}
 
 	if first := duration % cycle; first != 0 {
-		best = ConcurrentSearch(root, policies, first)
+		if best, stats, err = cycleSearch(ctx, ConcurrentSearch, root, policies, first); err != nil {
+			return best, stats, err
+		}
 		log.Printf("[meta] first (%v) : %g\n", cycle.Seconds(), best.Score())
 	}
 
+	// Every cycle is seeded anew in order not to replay the previous one.
+	seeds := newRand(root.conf.Seed)
+
 	cycles := duration / cycle
-	clone := CloneRoot(root)
-	for cycles > 0 {
-		best = ConcurrentSearch(clone, policies, cycle)
-		clone = CloneRoot(clone)
+	clone := reseed(CloneRoot(root), seeds.Int63())
+	for cycles > 0 && ctx.Err() == nil {
+		best, cycled, err = cycleSearch(ctx, ConcurrentSearch, clone, policies, cycle)
+		stats = stats.merge(cycled)
+		if err != nil {
+			return best, stats, err
+		}
+		clone = reseed(CloneRoot(clone), seeds.Int63())
 		log.Printf("[meta] cycle #%d (%v) : %g\n", cycles, cycle, best.Score())
 
 		cycles--
 	}
 
-	return best
+	return best, stats, nil
+}
+
+// reseed changes the seed of a fresh root, its configuration is copied.
+func reseed(root *Node, seed int64) *Node {
+	conf := *root.conf
+	conf.Seed = seed
+
+	root.conf = &conf
+
+	return root
+}
+
+// cycleSearch runs a search for at most one cycle.
+func cycleSearch(ctx context.Context, search Search, root *Node, policies []GamePolicy, cycle time.Duration) (Decision, Stats, error) {
+	ctx, cancel := context.WithTimeout(ctx, cycle)
+	defer cancel()
+	{
+		return search(ctx, root, policies)
+	}
 }
@@ -0,0 +1,281 @@
+// Copyright 2018 Erik Adelbert. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file publishes live metrics of the running searches. They are served over
+// HTTP as expvar JSON on /debug/vars and as Prometheus text on /metrics, next to
+// the pprof handlers of the gomer commands.
+
+package mcs
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics is a snapshot of a running search.
+type Metrics struct {
+	Search  int           `json:"search"` // identifies the search among running ones
+	Elapsed time.Duration `json:"elapsed"`
+
+	Nodes       int     `json:"nodes"`
+	NodeRate    float64 `json:"node_rate"` // nodes per second over the last rateWindow
+	Playouts    int64   `json:"playouts"`
+	PlayoutRate float64 `json:"playout_rate"` // playouts per second over the last rateWindow
+	Best        number  `json:"best"`         // score of the best decision, none yet is null
+
+	Queues   []Queue        `json:"queues,omitempty"`  // CMCT channels
+	Workers  *Workers       `json:"workers,omitempty"` // CMCT goroutines
+	Statuses map[string]int `json:"statuses"`          // tree nodes per NodeStatus
+}
+
+// Queue is the occupancy of a channel between stages of a search.
+type Queue struct {
+	Name     string `json:"name"`
+	Len      int    `json:"len"`
+	Capacity int    `json:"capacity"`
+}
+
+// queue watches a channel, see tracker.watch.
+type queue struct {
+	name     string
+	len      func() int
+	capacity int
+}
+
+// running registers the searches in progress.
+var running struct {
+	sync.Mutex
+
+	ids      int
+	trackers map[*tracker]int
+}
+
+// register makes a search visible to RunningMetrics until unregistered.
+func (t *tracker) register() {
+	running.Lock()
+	{
+		if running.trackers == nil {
+			running.trackers = make(map[*tracker]int)
+		}
+		running.ids++
+		running.trackers[t] = running.ids
+	}
+	running.Unlock()
+}
+
+func (t *tracker) unregister() {
+	running.Lock()
+	{
+		delete(running.trackers, t)
+	}
+	running.Unlock()
+}
+
+// watch publishes the occupancy of a channel of the search. The search is already
+// registered: queues are written under the lock of the running searches. The lock
+// of the tracker is not used, it is held while reporting progress.
+func (t *tracker) watch(name string, len func() int, capacity int) {
+	running.Lock()
+	{
+		t.queues = append(t.queues, queue{name: name, len: len, capacity: capacity})
+	}
+	running.Unlock()
+}
+
+// staff publishes the sizes of the pools of the search, see watch.
+func (t *tracker) staff(workers func() Workers) {
+	running.Lock()
+	{
+		t.workers = workers
+	}
+	running.Unlock()
+}
+
+// metrics returns a snapshot of the search.
+func (t *tracker) metrics(id int) Metrics {
+	now := time.Now()
+
+	m := Metrics{
+		Search:  id,
+		Elapsed: now.Sub(t.start),
+
+		Nodes:       int(atomic.LoadInt64(&t.nodes)),
+		NodeRate:    t.spawned.rate(now),
+		Playouts:    atomic.LoadInt64(&t.playouts),
+		PlayoutRate: t.window.rate(now),
+		Best:        number(math.Float64frombits(atomic.LoadUint64(&t.best))),
+
+		Statuses: t.root.count.counts(),
+	}
+
+	running.Lock()
+	queues, staffed := t.queues[:len(t.queues):len(t.queues)], t.workers
+	running.Unlock()
+
+	for _, q := range queues {
+		m.Queues = append(m.Queues, Queue{Name: q.name, Len: q.len(), Capacity: q.capacity})
+	}
+
+	if staffed != nil {
+		workers := staffed()
+		m.Workers = &workers
+	}
+
+	return m
+}
+
+// census counts the nodes of a tree by status. It is shared by all the nodes of
+// the tree and kept up to date as nodes are linked, pruned and change status:
+// metrics don't walk the tree.
+type census [null]int64 // atomic
+
+// add counts delta more nodes of the given status.
+func (c *census) add(status NodeStatus, delta int64) {
+	if c != nil && status >= 0 && status < null {
+		atomic.AddInt64(&c[status], delta)
+	}
+}
+
+// counts returns the number of nodes by status name, statuses of no node aside.
+func (c *census) counts() map[string]int {
+	counts := make(map[string]int)
+	if c == nil {
+		return counts
+	}
+
+	for status := range c {
+		if count := atomic.LoadInt64(&c[status]); count > 0 {
+			counts[NodeStatus(status).String()] = int(count)
+		}
+	}
+
+	return counts
+}
+
+// RunningMetrics returns a snapshot of every running search, in starting order.
+func RunningMetrics() []Metrics {
+	running.Lock()
+	ids := make(map[*tracker]int, len(running.trackers))
+	for t, id := range running.trackers {
+		ids[t] = id
+	}
+	running.Unlock()
+
+	metrics := make([]Metrics, 0, len(ids))
+	for t, id := range ids {
+		metrics = append(metrics, t.metrics(id))
+	}
+
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].Search < metrics[j].Search
+	})
+
+	return metrics
+}
+
+var serving sync.Once
+
+// ServeMetrics publishes RunningMetrics on http.DefaultServeMux: as the "mcs"
+// expvar on /debug/vars and in the Prometheus text format on /metrics.
+// It may be called more than once.
+func ServeMetrics() {
+	serving.Do(func() {
+		expvar.Publish("mcs", expvar.Func(func() interface{} {
+			return RunningMetrics()
+		}))
+
+		http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+			WritePrometheus(w, RunningMetrics())
+		})
+	})
+}
+
+// WritePrometheus writes metrics in the Prometheus text exposition format.
+// see https://prometheus.io/docs/instrumenting/exposition_formats/
+func WritePrometheus(w io.Writer, metrics []Metrics) error {
+	out := bufio.NewWriter(w)
+
+	family := func(name, kind, help string, samples func(m Metrics)) {
+		fmt.Fprintf(out, "# HELP mcs_%s %s\n", name, help)
+		fmt.Fprintf(out, "# TYPE mcs_%s %s\n", name, kind)
+		for _, m := range metrics {
+			samples(m)
+		}
+	}
+
+	family("elapsed_seconds", "gauge", "Time since the search started.", func(m Metrics) {
+		fmt.Fprintf(out, "mcs_elapsed_seconds{search=\"%d\"} %g\n", m.Search, m.Elapsed.Seconds())
+	})
+	family("nodes_total", "counter", "Nodes created by the search.", func(m Metrics) {
+		fmt.Fprintf(out, "mcs_nodes_total{search=\"%d\"} %d\n", m.Search, m.Nodes)
+	})
+	family("node_rate", "gauge", "Nodes created per second.", func(m Metrics) {
+		fmt.Fprintf(out, "mcs_node_rate{search=\"%d\"} %g\n", m.Search, m.NodeRate)
+	})
+	family("playouts_total", "counter", "Simulations completed by the search.", func(m Metrics) {
+		fmt.Fprintf(out, "mcs_playouts_total{search=\"%d\"} %d\n", m.Search, m.Playouts)
+	})
+	family("playout_rate", "gauge", "Simulations completed per second.", func(m Metrics) {
+		fmt.Fprintf(out, "mcs_playout_rate{search=\"%d\"} %g\n", m.Search, m.PlayoutRate)
+	})
+	family("best_score", "gauge", "Score of the best decision found.", func(m Metrics) {
+		best := float64(m.Best)
+		if math.IsInf(best, -1) {
+			best = math.NaN() // no decision yet
+		}
+		fmt.Fprintf(out, "mcs_best_score{search=\"%d\"} %s\n", m.Search, promFloat(best))
+	})
+	family("queue_length", "gauge", "Jobs waiting in a channel between stages.", func(m Metrics) {
+		for _, q := range m.Queues {
+			fmt.Fprintf(out, "mcs_queue_length{search=\"%d\",queue=%q} %d\n", m.Search, q.Name, q.Len)
+		}
+	})
+	family("queue_capacity", "gauge", "Capacity of a channel between stages.", func(m Metrics) {
+		for _, q := range m.Queues {
+			fmt.Fprintf(out, "mcs_queue_capacity{search=\"%d\",queue=%q} %d\n", m.Search, q.Name, q.Capacity)
+		}
+	})
+	family("workers", "gauge", "Goroutines of a stage of the pipeline.", func(m Metrics) {
+		if w := m.Workers; w != nil {
+			fmt.Fprintf(out, "mcs_workers{search=\"%d\",stage=\"walkers\"} %d\n", m.Search, w.Walkers)
+			fmt.Fprintf(out, "mcs_workers{search=\"%d\",stage=\"samplers\"} %d\n", m.Search, w.Samplers)
+			fmt.Fprintf(out, "mcs_workers{search=\"%d\",stage=\"updaters\"} %d\n", m.Search, w.Updaters)
+		}
+	})
+	family("tree_nodes", "gauge", "Nodes of the tree by status.", func(m Metrics) {
+		statuses := make([]string, 0, len(m.Statuses))
+		for status := range m.Statuses {
+			statuses = append(statuses, status)
+		}
+		sort.Strings(statuses)
+
+		for _, status := range statuses {
+			fmt.Fprintf(out, "mcs_tree_nodes{search=\"%d\",status=%q} %d\n", m.Search, status, m.Statuses[status])
+		}
+	})
+
+	return out.Flush()
+}
+
+// promFloat formats special values as Prometheus expects them.
+func promFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return fmt.Sprintf("%g", f)
+}
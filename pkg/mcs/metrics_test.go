@@ -0,0 +1,127 @@
+package mcs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunningMetrics(t *testing.T) {
+	items := make([]int, 20)
+	for i := range items {
+		items[i] = i
+	}
+	game := newToy(items...)
+
+	conf := NewConfig(0.03, 40, 0)
+	conf.VisitThreshold = 0
+
+	root := NewRoot(game.Clone(), conf)
+
+	var metrics []Metrics
+
+	// The search is sampled while running.
+	conf.Progress = func(p Progress) {
+		if p.Playouts > 100 && metrics == nil {
+			metrics = RunningMetrics()
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	ConcurrentSearch(ctx, root, []GamePolicy{nil})
+	cancel()
+
+	if len(RunningMetrics()) != 0 {
+		t.Errorf("metrics: stopped search still listed")
+	}
+
+	if len(metrics) != 1 {
+		t.Fatalf("metrics: expected 1 running search, got %d", len(metrics))
+	}
+	m := metrics[0]
+
+	if m.Nodes == 0 || m.Playouts == 0 || m.PlayoutRate <= 0 {
+		t.Errorf("metrics: unexpected %+v", m)
+	}
+
+	if len(m.Queues) != 2 || m.Queues[0].Name != "positions" || m.Queues[1].Name != "outcomes" {
+		t.Errorf("metrics: unexpected queues %+v", m.Queues)
+	}
+
+	total := 0
+	for _, count := range m.Statuses {
+		total += count
+	}
+	if total < m.Nodes {
+		t.Errorf("metrics: %d nodes counted by status, %d created", total, m.Nodes)
+	}
+
+	if _, err := json.Marshal(metrics); err != nil {
+		t.Errorf("metrics: %v", err)
+	}
+
+	var text bytes.Buffer
+	if err := WritePrometheus(&text, metrics); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sample := range []string{
+		"# TYPE mcs_nodes_total counter\n",
+		`mcs_nodes_total{search="`,
+		"# TYPE mcs_playouts_total counter\n",
+		`mcs_playouts_total{search="`,
+		`mcs_playout_rate{search="`,
+		`mcs_best_score{search="`,
+		`queue="positions"`,
+		`status="idle"`,
+	} {
+		if !strings.Contains(text.String(), sample) {
+			t.Errorf("metrics: %s missing from\n%s", sample, text.String())
+		}
+	}
+}
+
+func TestCensus(t *testing.T) {
+	items := make([]int, 12)
+	for i := range items {
+		items[i] = i
+	}
+	game := newToy(items...)
+
+	for _, test := range []struct {
+		name   string
+		config func(*Config)
+	}{
+		{"cmct", func(*Config) {}},
+		{"pruned cmct", func(conf *Config) { conf.MaxNodes = 100 }},
+		{"pruned cmct with transpositions", func(conf *Config) { conf.MaxNodes = 100; conf.Transpositions = true }},
+	} {
+		conf := NewConfig(0.03, 40, 0)
+		conf.VisitThreshold = 0
+		conf.Budget.Playouts = 2000
+		test.config(conf)
+
+		root := NewRoot(game.Clone(), conf)
+		_, stats, _ := ConcurrentSearch(context.Background(), root, []GamePolicy{nil})
+		if conf.MaxNodes > 0 && stats.Pruned == 0 {
+			t.Errorf("census: %s: nothing pruned", test.name)
+		}
+
+		want := make(map[string]int)
+		for _, node := range root.subtree() {
+			want[node.Status().String()]++
+		}
+
+		if got := root.count.counts(); !reflect.DeepEqual(got, want) {
+			t.Errorf("census: %s: counted %v, tree has %v", test.name, got, want)
+		}
+
+		if next := Reroot(root, root.Best()); next != nil && next.count.counts()["idle"] != len(next.subtree()) {
+			t.Errorf("census: %s: %v once rerooted, tree has %d nodes", test.name, next.count.counts(), len(next.subtree()))
+		}
+	}
+}
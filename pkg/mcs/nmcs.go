@@ -2,93 +2,155 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
+// This file implements a Nested Monte-Carlo Search (NMCS)
+
 package mcs
 
 import (
-	"fmt"
+	"context"
+	"math"
+	"math/rand"
 	"time"
 )
 
 const (
 	maxLevel = 4
+
+	// DefaultLevel is the nesting level of NestedSearch.
+	DefaultLevel = 2
 )
 
-// cazenave & jm
+// NestedSearch is a level 2 Nested Monte-Carlo Search from [2009 Cazenave].
+// Tree nodes are not used: the root only provides the initial position and records
+// the best sequence found.
 // see:
 // http://www.lamsade.dauphine.fr/~cazenave/papers/nested.pdf
 // https://www.researchgate.net/publication/48445151_Combining_UCT_and_Nested_Monte-Carlo_Search_for_Single-Player_General_Game_Playing
-func recursiveSearch(root *Node, policies []GamePolicy, duration time.Duration) Decision {
+func NestedSearch(ctx context.Context, root *Node, policies []GamePolicy) (Decision, Stats, error) {
+	return nestedSearch(ctx, root, policies, DefaultLevel)
+}
 
-	if root == nil {
-		// TODO: error handling
-		panic("no root")
+// NestedSearchLevel returns a Nested Monte-Carlo Search of the given level.
+// Level is bounded to [0, maxLevel], level 0 being a single playout.
+func NestedSearchLevel(level int) Search {
+	switch {
+	case level < 0:
+		level = 0
+	case level > maxLevel:
+		level = maxLevel
 	}
 
-	done := make(chan struct{})
-	timeout := make(chan bool)
+	return func(ctx context.Context, root *Node, policies []GamePolicy) (Decision, Stats, error) {
+		return nestedSearch(ctx, root, policies, level)
+	}
+}
 
-	go func() {
-		time.Sleep(duration)
-		close(timeout)
-	}()
+func nestedSearch(ctx context.Context, root *Node, policies []GamePolicy, level int) (Decision, Stats, error) {
+	if decision, err := check(root, policies); err != nil {
+		return decision, Stats{}, err
+	}
 
-	conclude := func(root *Node) Decision {
-		var final Decision
+	ctx, track := newTracker(ctx, root)
+	defer track.stop()
 
-		// Tracking
-		node := root
-		for len(node.down) > 0 {
-			node = node.down[0]
+	s := nmcs{policy: policies[0], rng: newRand(root.conf.Seed), track: track, top: level}
+	best := s.search(ctx, root.State().Clone(), level)
 
-			final.score += node.edge.Score()
-			final.Moves().Enqueue(node.edge)
-		}
+	return conclude(root, best, s.rng, policies[0], track), track.stats(), nil
+}
 
-		if moves := node.Best().Moves(); moves.Len() > 0 { // Leaf has been simulated
-			final.score += node.Best().Score()
-			final.Moves().Join(node.Best().Moves())
-		} else { // Leaf is terminal
-			final.score += node.State().Score()
+// conclude records the best sequence found by a search in the root and returns the
+// best sequence of the root, or the given one if it is a proof. When the deadline
+// has been met before a single sequence has been fully evaluated, a last playout is
+// needed to conclude.
+func conclude(root *Node, best Decision, rng *rand.Rand, policy GamePolicy, track *tracker) Decision {
+	if initial := root.State(); best.moves.Len() == 0 && initial.Moves().Len() > 0 {
+		best = simulate(context.Background(), rng, initial.Clone(), policy)
+		track.playout()
+	}
+	track.offer(best)
+
+	root.Lock()
+	root.seq.begin()
+	{
+		if best.Score() > root.best.Score() || root.best.moves.Len() == 0 {
+			root.best = best
+			root.top.store(best.score)
+		}
+		if !best.solved {
+			best = root.best
 		}
+	}
+	root.seq.end()
+	root.Unlock()
+
+	return best
+}
 
-		return final
+type nmcs struct {
+	policy GamePolicy
+	rng    *rand.Rand
+	track  *tracker
+	top    int // level of the search started from the root
+}
+
+// search plays the moves of the best sequence found so far at the given level,
+// it evaluates each legal move with a search of the level below. The best sequence
+// is memorized: it is followed when no better sequence is found.
+// Results obtained after ctx is done are discarded as they are truncated.
+func (s nmcs) search(ctx context.Context, state GameState, level int) Decision {
+	if level == 0 {
+		start := time.Now()
+		decision := simulate(ctx, s.rng, state, s.policy)
+		s.track.spent(sampling, start)
+		s.track.playout()
+
+		if ctx.Err() != nil { // cut off before the end of the game
+			return Decision{score: math.Inf(-1)}
+		}
+		return decision
 	}
 
-	var search func(root *Node, level int) Decision
-	search = func(root *Node, level int) Decision {
-		node := root
+	var played Decision // moves played so far at this level
 
-		var best Move
-		var top Decision
+	best := Decision{score: math.Inf(-1)}
 
-		_ = best
+	for moves := state.Moves(); moves.Len() > 0; moves = state.Moves() {
+		for _, move := range moves.List() {
+			if ctx.Err() != nil {
+				return best
+			}
 
-		if level == 0 { // best playout with memoization
-			for i := 0; i < 10; i++ {
-				for _, move := range node.Hand().List() {
-					start := node.State().Clone().Play(move)
-					simulated := start.Sample(done, policies[0])
+			sub := s.search(ctx, state.Clone().Play(move), level-1)
+			if ctx.Err() != nil {
+				return best
+			}
 
-					if simulated.Score() > top.Score() {
-						best = move
-						top = simulated
-					}
-				}
+			candidate := Decision{
+				moves: played.moves.Clone().Enqueue(move).Join(sub.moves),
+				score: played.score + move.Score() + sub.score,
 			}
-		} else {
 
-		}
+			if candidate.score > best.score {
+				best = candidate
 
-		if top.Score() > node.Best().Score() {
-			node.best = top
+				if level == s.top {
+					s.track.offer(best)
+				}
+			}
 		}
 
-		return conclude(root)
-	}
+		// Memorization: follow the best sequence.
+		move := best.moves[played.moves.Len()]
+		state = state.Play(move)
 
-	search(root, 0)
+		played.moves = played.moves.Enqueue(move)
+		played.score += move.Score()
+	}
 
-	fmt.Println(root.best)
+	if played.moves.Len() == 0 { // terminal position
+		return Decision{score: state.Score()}
+	}
 
-	return conclude(root)
+	return best
 }
@@ -0,0 +1,72 @@
+package mcs
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestNestedSearch(t *testing.T) {
+	game := newToy(5, 1, 4, 2, 3)
+
+	// At level 4, a 5 items game is exhaustively searched.
+	root := NewRoot(game.Clone(), NewConfig(0.03, 40, 0))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	result, _, _ := NestedSearchLevel(maxLevel)(ctx, root, []GamePolicy{nil})
+	cancel()
+
+	score, err := replay(game, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if score != result.Score() {
+		t.Errorf("nested: replayed %g, expected %g", score, result.Score())
+	}
+
+	if best := game.optimum(); result.Score() != best {
+		t.Errorf("nested: expected optimum %g, got %g", best, result.Score())
+	}
+}
+
+func TestNestedSearch_deadline(t *testing.T) {
+	game := newToy(5, 1, 4, 2, 3, 9, 7, 8)
+
+	for level := 0; level <= maxLevel; level++ {
+		root := NewRoot(game.Clone(), NewConfig(0.03, 40, 0))
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		result, _, _ := NestedSearchLevel(level)(ctx, root, []GamePolicy{nil})
+		cancel()
+
+		score, err := replay(game, result)
+		if err != nil {
+			t.Fatalf("level %d: %v", level, err)
+		}
+
+		if score != result.Score() {
+			t.Errorf("level %d: replayed %g, expected %g", level, score, result.Score())
+		}
+	}
+}
+
+func TestNestedSearch_cutoff(t *testing.T) {
+	game := newToy(5, 1, 4, 2)
+	root := NewRoot(game.Clone(), NewConfig(0.03, 40, 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, track := newTracker(ctx, root)
+	defer track.stop()
+	cancel()
+
+	// Playouts cut off by the deadline lack their end of game.
+	s := nmcs{rng: newRand(1), track: track}
+	if decision := s.search(ctx, game.Clone(), 0); !math.IsInf(decision.Score(), -1) {
+		t.Errorf("level 0: cut off playout kept, %v", decision)
+	}
+
+	r := nrpa{initial: game.Clone(), rng: newRand(1), track: track}
+	if decision, _ := r.search(ctx, 0, Weights{}); !math.IsInf(decision.Score(), -1) {
+		t.Errorf("level 0: cut off adaptive playout kept, %v", decision)
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2018 Erik Adelbert. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements a Nested Rollout Policy Adaptation (NRPA)
+
+package mcs
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	nrpaIterations = 100 // iterations per level
+	nrpaAlpha      = 1.0 // learning rate
+)
+
+// Weights is a policy learned over move codes. When passed to a sampler as a GamePolicy,
+// moves are chosen with a probability proportional to the exponential of their weight.
+type Weights map[uint64]float64
+
+// Coder is implemented by game states which are able to encode moves for policy learning.
+// Moves of games which do not implement it are coded by hashing their string.
+type Coder interface {
+	Code(Move) uint64
+}
+
+// Clone returns an independent copy of the calling weights.
+func (w Weights) Clone() Weights {
+	clone := make(Weights, len(w))
+	for k, v := range w {
+		clone[k] = v
+	}
+	return clone
+}
+
+// Pick chooses a move index among codes with a probability proportional to
+// the exponential of its weight (Gibbs sampling).
+func (w Weights) Pick(rng *rand.Rand, codes []uint64) int {
+	max := w.max(codes)
+
+	z := 0.0
+	probs := make([]float64, len(codes))
+	for i, code := range codes {
+		probs[i] = math.Exp(w[code] - max)
+		z += probs[i]
+	}
+
+	x := rng.Float64() * z
+	for i, p := range probs {
+		if x -= p; x < 0 {
+			return i
+		}
+	}
+	return len(codes) - 1
+}
+
+// max returns the largest weight of codes. Weights keep growing while adapted:
+// it's subtracted before exponentiating so that exponentials don't overflow
+// (log-sum-exp).
+func (w Weights) max(codes []uint64) float64 {
+	max := math.Inf(-1)
+	for _, code := range codes {
+		max = math.Max(max, w[code])
+	}
+	return max
+}
+
+// AdaptiveSearch is a level 3 Nested Rollout Policy Adaptation from [2011 Rosin].
+// A policy over move codes is learned while searching. When policies[0] is Weights,
+// it seeds the search, any other policy is ignored. The seed is only read: it may
+// be shared with concurrent searches. See LearnPolicy to get the learned policy.
+// Games are expected to accept Weights as a sampling policy.
+// see:
+// https://www.ijcai.org/Proceedings/11/Papers/115.pdf
+// http://www.lamsade.dauphine.fr/~cazenave/papers/nrpaorg.pdf
+func AdaptiveSearch(ctx context.Context, root *Node, policies []GamePolicy) (Decision, Stats, error) {
+	return adaptiveSearch(ctx, root, policies, 3)
+}
+
+// AdaptiveSearchLevel returns a Nested Rollout Policy Adaptation of the given level.
+// Level is bounded to [1, maxLevel].
+func AdaptiveSearchLevel(level int) Search {
+	switch {
+	case level < 1:
+		level = 1
+	case level > maxLevel:
+		level = maxLevel
+	}
+
+	return func(ctx context.Context, root *Node, policies []GamePolicy) (Decision, Stats, error) {
+		return adaptiveSearch(ctx, root, policies, level)
+	}
+}
+
+func adaptiveSearch(ctx context.Context, root *Node, policies []GamePolicy, level int) (Decision, Stats, error) {
+	if decision, err := check(root, policies); err != nil {
+		return decision, Stats{}, err
+	}
+
+	seed, _ := policies[0].(Weights)
+	decision, _, stats, err := LearnPolicy(ctx, root, seed, level)
+
+	return decision, stats, err
+}
+
+// LearnPolicy is AdaptiveSearchLevel seeded with the given policy, nil being the
+// uniform policy. It also returns the learned policy which can later be used by
+// other searches as a sampling policy. The seed is left unchanged.
+func LearnPolicy(ctx context.Context, root *Node, seed Weights, level int) (Decision, Weights, Stats, error) {
+	if decision, err := check(root, []GamePolicy{seed}); err != nil {
+		return decision, seed, Stats{}, err
+	}
+
+	switch {
+	case level < 1:
+		level = 1
+	case level > maxLevel:
+		level = maxLevel
+	}
+
+	ctx, track := newTracker(ctx, root)
+	defer track.stop()
+
+	s := nrpa{initial: root.State().Clone(), rng: newRand(root.conf.Seed), track: track, top: level}
+	best, learned := s.search(ctx, level, seed)
+	best = conclude(root, best, s.rng, learned, track)
+
+	return best, learned, track.stats(), nil
+}
+
+type nrpa struct {
+	initial GameState
+	rng     *rand.Rand
+	track   *tracker
+	top     int // level of the search started from the root
+}
+
+// search returns the best sequence found at the given level and the policy
+// adapted toward it. Results obtained after ctx is done are discarded.
+// Policies are only read, adapt returns new ones: playouts share them.
+func (s nrpa) search(ctx context.Context, level int, policy Weights) (Decision, Weights) {
+	if level == 0 {
+		start := time.Now()
+		decision := simulate(ctx, s.rng, s.initial.Clone(), policy)
+		s.track.spent(sampling, start)
+		s.track.playout()
+
+		if ctx.Err() != nil { // cut off before the end of the game
+			return Decision{score: math.Inf(-1)}, policy
+		}
+		return decision, policy
+	}
+
+	best := Decision{score: math.Inf(-1)}
+	for i := 0; i < nrpaIterations; i++ {
+		if ctx.Err() != nil {
+			break
+		}
+
+		sampled, _ := s.search(ctx, level-1, policy)
+		if ctx.Err() != nil {
+			break
+		}
+
+		if sampled.score >= best.score {
+			best = sampled
+
+			if level == s.top {
+				s.track.offer(best)
+			}
+		}
+
+		policy = s.adapt(policy, best)
+	}
+
+	return best, policy
+}
+
+// adapt moves the policy toward the given sequence by gradient ascent.
+func (s nrpa) adapt(policy Weights, best Decision) Weights {
+	adapted := policy.Clone()
+
+	state := s.initial.Clone()
+	for _, move := range best.moves {
+		moves := state.Moves().List()
+
+		codes := make([]uint64, len(moves))
+		for i, m := range moves {
+			codes[i] = Code(state, m)
+		}
+		max := policy.max(codes)
+
+		z := 0.0
+		for _, c := range codes {
+			z += math.Exp(policy[c] - max)
+		}
+
+		adapted[Code(state, move)] += nrpaAlpha
+		for _, c := range codes {
+			adapted[c] -= nrpaAlpha * math.Exp(policy[c]-max) / z
+		}
+
+		state = state.Play(move)
+	}
+
+	return adapted
+}
+
+// Code returns the code of a move played in the given state.
+func Code(state GameState, m Move) uint64 {
+	if coder, ok := state.(Coder); ok {
+		return coder.Code(m)
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(m.String())) // never fails
+	return h.Sum64()
+}
@@ -0,0 +1,92 @@
+package mcs
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWeights_Pick(t *testing.T) {
+	w := Weights{1: 0, 2: math.Log(3)}
+	codes := []uint64{1, 2}
+	rng := newRand(1)
+
+	const n = 40000
+	count := [2]float64{}
+	for i := 0; i < n; i++ {
+		count[w.Pick(rng, codes)]++
+	}
+
+	// expected frequencies are 1/4 and 3/4
+	if p := count[1] / n; math.Abs(p-0.75) > 0.02 {
+		t.Errorf("pick: expected frequency 0.75, got %g", p)
+	}
+
+	// Large weights don't overflow.
+	w = Weights{1: 1000, 2: 1000 + math.Log(3)}
+	count = [2]float64{}
+	for i := 0; i < n; i++ {
+		count[w.Pick(rng, codes)]++
+	}
+
+	if p := count[1] / n; math.Abs(p-0.75) > 0.02 {
+		t.Errorf("pick: large weights, expected frequency 0.75, got %g", p)
+	}
+}
+
+func TestNRPA_adapt(t *testing.T) {
+	game := newToy(5, 1, 4)
+	s := nrpa{initial: game.Clone()}
+
+	policy := Weights{}
+	for _, move := range game.Moves().List() {
+		policy[Code(game, move)] = 1000
+	}
+
+	best := Decision{moves: MoveSequence(game.Moves().List())}
+	for code, weight := range s.adapt(policy, best) {
+		if math.IsNaN(weight) || math.IsInf(weight, 0) {
+			t.Fatalf("adapt: weight of %d is %g", code, weight)
+		}
+	}
+
+	if policy[Code(game, best.moves[0])] != 1000 {
+		t.Errorf("adapt: policy changed")
+	}
+}
+
+func TestAdaptiveSearch(t *testing.T) {
+	game := newToy(5, 1, 4, 2, 3)
+
+	seed := Weights{}
+
+	root := NewRoot(game.Clone(), NewConfig(0.03, 40, 0))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	result, learned, _, _ := LearnPolicy(ctx, root, seed, 2)
+	cancel()
+
+	if len(seed) != 0 {
+		t.Errorf("nrpa: seed policy changed")
+	}
+
+	score, err := replay(game, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if score != result.Score() {
+		t.Errorf("nrpa: replayed %g, expected %g", score, result.Score())
+	}
+
+	if len(learned) == 0 {
+		t.Errorf("nrpa: no policy learned")
+	}
+
+	// The learned policy favors the best sequence.
+	best := game.Moves().List()[0] // lowest item
+	worst := game.Moves().List()[4]
+	if learned[Code(game, best)] <= learned[Code(game, worst)] {
+		t.Errorf("nrpa: %v is not preferred over %v", best, worst)
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2018 Erik Adelbert. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements move by move play: the first move of a decision is committed
+// and the subtree of the resulting position becomes the root of the next search.
+// This is how games are played against per-move clocks.
+
+package mcs
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// OnlineSearch turns a search into a move by move player. Each move is searched for
+// at most perMove, the first move of the resulting decision is committed and the
+// subtree of the new position is searched in turn with its statistics. The budget of
+// the configuration, if any, is spent on each move and progress reports are relative
+// to the current position. The game ends once the position is solved or terminal, or
+// when ctx is done: the last decision then completes the committed moves.
+// A zero perMove leaves moves bounded by the budget only. Statistics are summed
+// up over moves.
+func OnlineSearch(search Search, perMove time.Duration) Search {
+	return func(ctx context.Context, root *Node, policies []GamePolicy) (Decision, Stats, error) {
+		var played Decision
+		var stats Stats
+
+		for {
+			var decision Decision
+			var searched Stats
+			var err error
+			if perMove > 0 {
+				decision, searched, err = cycleSearch(ctx, search, root, policies, perMove)
+			} else {
+				decision, searched, err = search(ctx, root, policies)
+			}
+			stats = stats.merge(searched)
+
+			switch {
+			case errors.Is(err, ErrNoMoves) && played.Moves().Len() > 0:
+				return played.Join(decision), stats, nil // game over
+			case err != nil:
+				return decision, stats, err
+			case decision.Moves().Len() == 0 && !root.IsTerminal() && ctx.Err() == nil:
+				continue // nothing found yet, search on
+			case decision.Solved() || decision.Moves().Len() == 0 || ctx.Err() != nil:
+				if played.Moves().Len() == 0 {
+					return decision, stats, nil
+				}
+				return played.Join(decision), stats, nil
+			}
+
+			move := decision.Moves()[0]
+			played.moves = played.moves.Enqueue(move)
+			played.score += move.Score()
+
+			root = Reroot(root, decision)
+		}
+	}
+}
+
+// Reroot commits the first move of a decision made from the calling root. The child
+// reached by this move becomes a new root: its subtree is kept with its statistics
+// and its ancestors are released for the garbage collector. Scores recorded in the
+// subtree are made relative to the new root, approximately so for transposed nodes
+// which have been reached through other first moves. The rest of the decision is the
+// best decision of the new root unless a better one has been recorded. Reroot returns
+// nil when the decision has no moves.
+// The calling tree must not be searched while rerooted nor used afterwards.
+func Reroot(root *Node, decision Decision) *Node {
+	if decision.Moves().Len() == 0 {
+		return nil
+	}
+
+	move, rest := decision.Moves().Dequeue()
+
+	var next *Node
+	for _, child := range root.Down() {
+		if edge := root.edgeTo(child); edge.String() == move.String() {
+			next = child
+			break
+		}
+	}
+
+	if next != nil {
+		next.detach(move.Score())
+	} else { // not expanded yet
+		next = NewRoot(root.State().Clone().Play(move), root.conf)
+	}
+
+	best := Decision{
+		moves:  rest.Clone(),
+		score:  decision.Score() - move.Score(),
+		solved: decision.Solved(),
+	}
+
+	if next.Visits() == 0 || best.score >= next.Best().score {
+		next.best = best
+		next.top.store(best.score)
+	}
+
+	return next
+}
+
+// detach makes the calling node the root of its own subtree. Parents outside of the
+// subtree are unlinked, depths are computed anew and recorded scores are shifted
+// by the score of the committed move. Statuses of interrupted searches are reset.
+// The transposition table and the census of statuses are rebuilt with the subtree
+// nodes only.
+func (n *Node) detach(shift float64) {
+	nodes := n.subtree()
+
+	in := make(map[*Node]bool, len(nodes))
+	for _, node := range nodes {
+		in[node] = true
+	}
+
+	var tt *table
+	if n.table != nil {
+		tt = newTable()
+	}
+	count := &census{}
+
+	for _, node := range nodes {
+		var links []*Node
+		for _, parent := range node.links {
+			if in[parent] {
+				links = append(links, parent)
+			}
+		}
+
+		// A transposed node whose primary parent is gone is adopted by another parent.
+		if node != n && !in[node.up] {
+			parent := links[0]
+			node.up, node.edge, links = parent, parent.via[node], links[1:]
+			delete(parent.via, node)
+		}
+		node.links = links
+
+		node.SetStatus(idle)
+		node.pending = 0
+		node.mean.store(node.mean.load() - shift)
+		node.worst.store(node.worst.load() - shift)
+		node.best.score -= shift
+		node.top.store(node.best.score)
+		if node.best.moves.Len() > 0 {
+			node.best.moves = node.best.moves[1:]
+		}
+
+		node.count = count
+		count.add(idle, 1)
+
+		node.table = tt
+		if tt != nil {
+			tt.LoadOrStore(node.state.(Hasher).Hash(), node)
+		}
+	}
+
+	n.up, n.edge, n.links = nil, nil, nil
+
+	depths := map[*Node]int{n: 0}
+	var depth func(*Node) int
+	depth = func(node *Node) int {
+		if d, ok := depths[node]; ok {
+			return d
+		}
+		d := depth(node.up) + 1
+		depths[node] = d
+		return d
+	}
+
+	for _, node := range nodes {
+		node.depth = depth(node)
+	}
+}
+
+// subtree lists the distinct nodes reachable from the calling node, itself included.
+func (n *Node) subtree() []*Node {
+	seen := map[*Node]bool{n: true}
+	nodes := []*Node{n}
+
+	for i := 0; i < len(nodes); i++ {
+		for _, child := range nodes[i].Down() {
+			if !seen[child] {
+				seen[child] = true
+				nodes = append(nodes, child)
+			}
+		}
+	}
+
+	return nodes
+}
@@ -0,0 +1,108 @@
+package mcs
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+	"weak"
+)
+
+func TestReroot(t *testing.T) {
+	game := newToy(6, 1, 5, 2, 4, 3)
+
+	conf := NewConfig(0.03, 40, 0)
+	conf.Seed = 1
+	conf.Budget = Budget{Playouts: 300}
+	conf.Transpositions = true
+
+	root := NewRoot(game.Clone(), conf)
+	result, _, _ := ConfidentSearch(context.Background(), root, []GamePolicy{nil})
+
+	move := result.Moves()[0]
+
+	var child *Node
+	for _, node := range root.Down() {
+		if root.edgeTo(node).String() == move.String() {
+			child = node
+		}
+	}
+	visits, mean := child.Visits(), child.Mean()
+
+	old := weak.Make(root)
+
+	next := Reroot(root, result)
+	root = nil
+
+	if next != child {
+		t.Fatalf("reroot: expected child %p, got %p", child, next)
+	}
+
+	if next.Up() != nil || next.Edge() != nil || next.Depth() != 0 {
+		t.Errorf("reroot: new root still attached\n%v", next)
+	}
+
+	if next.Visits() != visits || next.Mean() != mean-move.Score() {
+		t.Errorf("reroot: expected %g visits of mean %g, got %g of mean %g",
+			visits, mean-move.Score(), next.Visits(), next.Mean())
+	}
+
+	nodes := next.subtree()
+	for _, node := range nodes[1:] {
+		if up := node.Up(); up.Depth() != node.Depth()-1 {
+			t.Errorf("reroot: node at depth %d below a node at depth %d", node.Depth(), up.Depth())
+		}
+	}
+
+	if n := next.table.Len(); n != len(nodes) {
+		t.Errorf("reroot: expected %d positions in table, got %d", len(nodes), n)
+	}
+
+	state := game.Clone().Play(move)
+	score, err := replay(state, next.Best())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if score != next.Best().Score() || score != result.Score()-move.Score() {
+		t.Errorf("reroot: replayed %g, expected %g", score, next.Best().Score())
+	}
+
+	// The old root is garbage collected.
+	runtime.GC()
+	if old.Value() != nil {
+		t.Errorf("reroot: ancestors not released")
+	}
+
+	if Reroot(next, Decision{}) != nil {
+		t.Errorf("reroot: new root without moves")
+	}
+}
+
+func TestOnlineSearch(t *testing.T) {
+	game := newToy(7, 1, 6, 2, 5, 3, 4)
+
+	conf := NewConfig(0.03, 40, 0)
+	conf.Budget = Budget{Playouts: 50}
+
+	root := NewRoot(game.Clone(), conf)
+
+	online := OnlineSearch(ConcurrentSearch, time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	result, _, _ := online(ctx, root, []GamePolicy{nil})
+	cancel()
+
+	if n := result.Moves().Len(); n != len(game.items) {
+		t.Fatalf("online: expected %d moves, got %d", len(game.items), n)
+	}
+
+	score, err := replay(game, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if score != result.Score() {
+		t.Errorf("online: replayed %g, expected %g", score, result.Score())
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright 2018 Erik Adelbert. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements root and leaf parallelization of UCT, to be compared with
+// the tree parallelization of CMCT.
+// see https://dke.maastrichtuniversity.nl/m.winands/documents/multithreadedMCTS2.pdf
+
+package mcs
+
+import (
+	"context"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// RootParallelSearch runs as many independent ConfidentSearch as there are CPUs.
+func RootParallelSearch(ctx context.Context, root *Node, policies []GamePolicy) (Decision, Stats, error) {
+	return rootParallelSearch(ctx, root, policies, runtime.NumCPU())
+}
+
+// RootParallelSearchTrees returns a root parallel search of the given number of
+// trees, at least one. Every tree is searched by ConfidentSearch from a copy of the
+// root with its own seed, the budget of the root is shared by all trees. Once done,
+// the statistics of the first moves of every tree are merged into the root and the
+// best decision of all trees is returned.
+// see [2008 Chaslot, Winands et al.]
+func RootParallelSearchTrees(trees int) Search {
+	if trees < 1 {
+		trees = 1
+	}
+
+	return func(ctx context.Context, root *Node, policies []GamePolicy) (Decision, Stats, error) {
+		return rootParallelSearch(ctx, root, policies, trees)
+	}
+}
+
+func rootParallelSearch(ctx context.Context, root *Node, policies []GamePolicy, trees int) (Decision, Stats, error) {
+	if decision, err := check(root, policies); err != nil {
+		return decision, Stats{}, err
+	}
+
+	tree := GrowTree(root)
+
+	ctx, track := newTracker(ctx, tree)
+	defer track.stop()
+
+	seeds := newRand(tree.conf.Seed)
+
+	roots := make([]*Node, trees)
+	for i := range roots {
+		conf := *tree.conf
+		conf.Seed = seeds.Int63()
+		conf.Budget = Budget{} // enforced by the tracker of the root
+		conf.Progress = nil    // reported by the tracker of the root
+
+		roots[i] = NewRoot(tree.State().Clone(), &conf)
+	}
+
+	var wg sync.WaitGroup
+
+	decisions := make([]Decision, trees)
+	wg.Add(trees)
+	for i := range roots {
+		go func(i int) {
+			decisions[i], _, _ = ConfidentSearch(ctx, roots[i], policies)
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+
+	for _, other := range roots {
+		tree.merge(other)
+	}
+
+	// The root has recorded the best decision of every tree, a proof is preferred.
+	// A tree concluded without a single playout back propagated is not merged: its
+	// decision is kept if the root has none.
+	best := decide(tree)
+	for _, decision := range decisions {
+		switch {
+		case best.Solved():
+		case decision.Solved(), best.Moves().Len() == 0, decision.Score() > best.Score():
+			best = decision
+		}
+	}
+
+	return best, track.stats(), nil
+}
+
+// merge adds the statistics of the root of another tree grown from the same position
+// and of its children to the calling root and to its children. The best decision of
+// the other root is kept if better. UCB values of children are computed anew.
+func (n *Node) merge(other *Node) {
+	n.absorb(other)
+
+	children := make(map[string]*Node)
+	for _, child := range n.Down() {
+		children[n.edgeTo(child).String()] = child
+	}
+
+	for _, child := range other.Down() {
+		if mine, ok := children[other.edgeTo(child).String()]; ok {
+			mine.absorb(child)
+		}
+	}
+
+	for _, child := range n.Down() {
+		if child.Visits() > 0 {
+			child.Evaluate()
+		}
+	}
+}
+
+// absorb pools the statistics of another node of the same position into the
+// calling node: visits are summed up, mean and variance are combined as if all
+// the simulations had run through the calling node.
+// see https://en.wikipedia.org/wiki/Algorithms_for_calculating_variance#Parallel_algorithm
+func (n *Node) absorb(other *Node) {
+	other.Lock()
+	s, best := other.statistics(), other.best
+	other.Unlock()
+
+	if s.visits == 0 {
+		return
+	}
+
+	n.Lock()
+	n.seq.begin()
+	{
+		visits, mean := n.visits.load(), n.mean.load()
+
+		if visits == 0 {
+			n.best = best
+			n.top.store(best.score)
+			n.worst.store(s.worst)
+		} else {
+			if best.score > n.best.score {
+				n.best = best
+				n.top.store(best.score)
+			}
+			n.worst.store(math.Min(n.worst.load(), s.worst))
+		}
+
+		total := visits + s.visits
+		δ := s.mean - mean
+
+		n.mean.store(mean + δ*s.visits/total)
+		n.variance.store(n.variance.load() + s.variance + δ*δ*visits*s.visits/total)
+		n.visits.store(total)
+	}
+	n.seq.end()
+	n.Unlock()
+}
+
+// LeafParallelSearch is ConfidentSearch running as many simulations from each leaf
+// as there are CPUs.
+func LeafParallelSearch(ctx context.Context, root *Node, policies []GamePolicy) (Decision, Stats, error) {
+	return leafParallelSearch(ctx, root, policies, runtime.NumCPU())
+}
+
+// LeafParallelSearchWidth returns a leaf parallel search running the given number of
+// simulations, at least one, from each leaf at once. Their outcomes are all back
+// propagated before the next selection, none once the deadline has cut them off.
+// see [2007 Cazenave, Jouandeau]
+func LeafParallelSearchWidth(width int) Search {
+	if width < 1 {
+		width = 1
+	}
+
+	return func(ctx context.Context, root *Node, policies []GamePolicy) (Decision, Stats, error) {
+		return leafParallelSearch(ctx, root, policies, width)
+	}
+}
+
+func leafParallelSearch(ctx context.Context, root *Node, policies []GamePolicy, width int) (Decision, Stats, error) {
+	if decision, err := check(root, policies); err != nil {
+		return decision, Stats{}, err
+	}
+
+	tree := GrowTree(root)
+
+	ctx, track := newTracker(ctx, tree)
+	defer track.stop()
+
+	done := ctx.Done()
+
+	seeds := newRand(tree.conf.Seed)
+	rng := newRand(seeds.Int63()) // selections and expansions
+	rngs := newRands(seeds, width)
+
+	sampled := make([]Decision, width)
+
+	for {
+		select {
+
+		case <-done:
+			return conclude(tree, decide(tree), rng, policies[0], track), track.stats(), nil
+
+		default:
+			if tree.IsSolved() {
+				return decide(tree), track.stats(), nil
+			}
+			track.trim()
+
+			var score float64
+			var moves MoveSequence
+
+			node := tree
+			path := []*Node{tree}
+
+			start := time.Now()
+			for node.IsExpanded() {
+				next, oversampled := node.downselect(rng)
+				if oversampled {
+					track.oversampled()
+				}
+
+				move := node.edgeTo(next)
+				moves = moves.Enqueue(move)
+
+				score += move.Score()
+
+				node = next
+				path = append(path, node)
+			}
+
+			if !node.IsTerminal() && node.Visits() > node.conf.VisitThreshold && track.reserve() {
+				move := node.RandomNewEdge(rng)
+				parent := node
+				if node = node.ExpandOne(move); node.Up() == parent {
+					track.created()
+				} else {
+					track.release() // transposition
+				}
+
+				moves = moves.Enqueue(move)
+
+				score += move.Score()
+
+				path = append(path, node)
+			}
+			track.reached(len(path) - 1)
+			track.spent(walking, start)
+
+			// The search has been stopped while walking, eg. the expansion has
+			// used up the nodes budget: a playout would be cut off.
+			if ctx.Err() != nil {
+				continue
+			}
+
+			var wg sync.WaitGroup
+
+			wg.Add(width)
+			for i := range sampled {
+				go func(i int) {
+					start := time.Now()
+					sampled[i] = simulate(ctx, rngs[i], node.State().Clone(), policies[0])
+					track.spent(sampling, start)
+					wg.Done()
+				}(i)
+			}
+			wg.Wait()
+
+			// Playouts cut off by the deadline lack their end of game.
+			if ctx.Err() != nil {
+				continue
+			}
+
+			for _, decision := range sampled {
+				decision.moves = moves.Clone().Join(decision.moves)
+				decision.score += score
+
+				if track.playout() {
+					start = time.Now()
+					backup(path, decision)
+					track.spent(updating, start)
+					track.offer(decision)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package mcs
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParallelSearch(t *testing.T) {
+	game := newToy(4, 1, 3, 2)
+
+	for _, search := range []Search{RootParallelSearchTrees(3), LeafParallelSearchWidth(3)} {
+		root := NewRoot(game.Clone(), NewConfig(0.03, 40, 0))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		result, _, _ := search(ctx, root, []GamePolicy{nil})
+		cancel()
+
+		if !result.Solved() {
+			t.Fatalf("%v: small game not solved", search)
+		}
+
+		if best := game.optimum(); result.Score() != best {
+			t.Errorf("%v: expected proven optimum %g, got %g", search, best, result.Score())
+		}
+
+		score, err := replay(game, result)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if score != result.Score() {
+			t.Errorf("%v: replayed %g, expected %g", search, score, result.Score())
+		}
+	}
+}
+
+func TestParallelSearch_budget(t *testing.T) {
+	items := make([]int, 20)
+	for i := range items {
+		items[i] = i
+	}
+	game := newToy(items...)
+
+	for _, search := range []Search{RootParallelSearchTrees(3), LeafParallelSearchWidth(3)} {
+		conf := NewConfig(0.03, 40, 0)
+		conf.Budget = Budget{Playouts: 600}
+
+		root := NewRoot(game.Clone(), conf)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		result, stats, _ := search(ctx, root, []GamePolicy{nil})
+		if ctx.Err() != nil {
+			t.Errorf("%v: budget not enforced", search)
+		}
+		cancel()
+
+		if stats.Playouts != 600 {
+			t.Errorf("%v: expected 600 playouts, got %d", search, stats.Playouts)
+		}
+
+		// Root statistics account for the simulations of every tree.
+		if v := root.Visits(); v != 600 {
+			t.Errorf("%v: expected 600 visits, got %g", search, v)
+		}
+
+		var visits float64
+		for _, child := range root.Down() {
+			visits += child.Visits()
+		}
+		if visits != 600 {
+			t.Errorf("%v: expected 600 visits of first moves, got %g", search, visits)
+		}
+
+		if result.Score() != root.Best().Score() {
+			t.Errorf("%v: decision %g, best recorded %g", search, result.Score(), root.Best().Score())
+		}
+	}
+}
+
+func TestParallelSearch_cutoff(t *testing.T) {
+	items := make([]int, 200)
+	for i := range items {
+		items[i] = i
+	}
+	game := newToy(items...)
+
+	// Deadlines cut playouts off: the decision is complete anyway.
+	for _, search := range []Search{RootParallelSearchTrees(3), LeafParallelSearchWidth(3)} {
+		for i, timeout := range []time.Duration{0, 10 * time.Microsecond, 100 * time.Microsecond, time.Millisecond, 5 * time.Millisecond} {
+			conf := NewConfig(0.03, 40, 0)
+			conf.Seed = int64(i)
+			root := NewRoot(game.Clone(), conf)
+
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			result, _, _ := search(ctx, root, []GamePolicy{nil})
+			cancel()
+
+			score, err := replay(game, result)
+			if err != nil {
+				t.Fatalf("%v: %v: %v", search, timeout, err)
+			}
+			if score != result.Score() {
+				t.Errorf("%v: %v: replayed %g, expected %g", search, timeout, score, result.Score())
+			}
+		}
+	}
+}
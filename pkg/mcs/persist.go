@@ -0,0 +1,607 @@
+// Copyright 2018 Erik Adelbert. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements trees persistence: a tree is saved with its statistics,
+// its untried hands and its configuration then loaded back in order to resume
+// a search. Positions are not saved, they are rebuilt by replaying edges from
+// the initial position. Moves are saved as indexes in the lists of legal moves,
+// games are expected to list moves in a reproducible order.
+
+package mcs
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	treeMagic   = "MCST"
+	treeVersion = 1
+
+	// minNodeSize bounds the size of a saved node from below: its statistics
+	// alone take 9 floats.
+	minNodeSize = 9 * 8
+)
+
+// SaveTree writes the tree grown from root. It may be called during a search:
+// nodes are then saved one at a time and the snapshot is only loosely consistent.
+// Best decisions are saved by score only, except for the root one. Game policies
+// and the Progress callback are not saved. The UCB formula is saved by name: a tree
+// searched with a formula of another package can't be saved.
+func SaveTree(w io.Writer, root *Node) error {
+	if root == nil {
+		return ErrNilRoot
+	}
+
+	if ucb := root.conf.UCB; !registered(ucb) {
+		return fmt.Errorf("mcs: UCB formula %q can't be saved", ucb)
+	}
+
+	nodes, ups, downs := root.snapshot()
+
+	ids := make(map[*Node]uint64, len(nodes))
+	for i, node := range nodes {
+		ids[node] = uint64(i)
+	}
+
+	enc := newEncoder(w)
+
+	enc.string(treeMagic)
+	enc.uvarint(treeVersion)
+	enc.config(root.conf)
+
+	h, ok := root.State().(Hasher)
+	enc.bool(ok)
+	if ok {
+		enc.uint64(h.Hash())
+	}
+
+	enc.uvarint(uint64(len(nodes)))
+	for i, node := range nodes {
+		state := node.State()
+
+		var moves []Move
+		index := func(move Move) uint64 {
+			if moves == nil {
+				moves = state.Moves().List()
+			}
+			return moveIndex(moves, move, &enc.err)
+		}
+
+		var untried []Move
+
+		node.Lock()
+		arity, best, proven := node.arity, node.best, node.proven
+		if n := node.hand.Len(); n != arity && n != 0 { // partially drawn
+			untried = node.hand.List()
+		}
+		left := node.hand.Len()
+		stats := [...]float64{
+			node.solved, node.exact,
+			node.value.load(), node.mean.load(), node.visits.load(), node.variance.load(), node.worst.load(),
+			node.ε, best.score,
+		}
+		node.Unlock()
+
+		if i > 0 {
+			enc.uvarint(ids[ups[i]])
+		}
+
+		enc.uvarint(uint64(arity))
+		enc.uvarint(uint64(left))
+		for _, move := range untried {
+			enc.uvarint(index(move))
+		}
+
+		enc.bool(proven)
+		enc.bool(best.solved)
+		for _, stat := range stats {
+			enc.float(stat)
+		}
+
+		if i == 0 { // the root decision is replayed from the initial position
+			enc.uvarint(uint64(best.moves.Len()))
+
+			replayed := state.Clone()
+			for _, move := range best.moves {
+				enc.uvarint(moveIndex(replayed.Moves().List(), move, &enc.err))
+				replayed = replayed.Play(move)
+			}
+		}
+
+		enc.uvarint(uint64(len(downs[i])))
+		for _, child := range downs[i] {
+			enc.uvarint(ids[child])
+			enc.uvarint(index(node.edgeTo(child)))
+		}
+	}
+
+	return enc.flush()
+}
+
+// LoadTree reads a tree saved by SaveTree and returns its root. The initial position
+// has to be the position of the saved root, the positions of the other nodes are
+// rebuilt from it. The configuration is loaded with the tree, see Node.Config. A
+// search resumes when called on the loaded root.
+func LoadTree(r io.Reader, initial GameState) (*Node, error) {
+	if initial == nil {
+		return nil, ErrNilRoot
+	}
+
+	dec := newDecoder(r)
+
+	if magic := dec.string(); dec.err == nil && magic != treeMagic {
+		return nil, errors.New("mcs: not a tree file")
+	}
+
+	if version := dec.uvarint(); dec.err == nil && version != treeVersion {
+		return nil, fmt.Errorf("mcs: unsupported tree version %d", version)
+	}
+
+	conf := dec.config()
+
+	if hashed := dec.bool(); hashed {
+		hash := dec.uint64()
+		if h, ok := initial.(Hasher); ok && dec.err == nil && h.Hash() != hash {
+			return nil, errors.New("mcs: tree saved from another position")
+		}
+	}
+
+	if dec.err != nil {
+		return nil, dec.err
+	}
+
+	// Sizes read from the input are bounded before anything is allocated: the
+	// count of nodes by the input left, when known, and the moves of a node by
+	// its legal moves.
+	count := dec.uvarint()
+	if left := dec.left(); dec.err == nil && left >= 0 && count > uint64(left/minNodeSize) {
+		dec.fail()
+	}
+	if dec.err != nil {
+		return nil, dec.err
+	}
+
+	var nodes []*Node
+	var downs [][]uint64
+	var vias [][]Move
+
+	edges := make(map[[2]uint64]Move) // moves from parents to nodes still to be read
+
+	for i := uint64(0); i < count && dec.err == nil; i++ {
+		var up *Node
+		var edge Move
+		var state GameState
+
+		if i == 0 {
+			state = initial.Clone()
+		} else {
+			id := dec.uvarint()
+			if edge = edges[[2]uint64{id, i}]; id >= i || edge == nil {
+				dec.fail()
+				break
+			}
+
+			up = nodes[id]
+			state = up.State().Clone().Play(edge)
+		}
+
+		var moves []Move
+		move := func(index uint64) Move {
+			if moves == nil {
+				moves = state.Moves().List()
+			}
+			if index >= uint64(len(moves)) {
+				dec.fail()
+				return nil
+			}
+			return moves[index]
+		}
+
+		legal := state.Moves()
+
+		arity := dec.size(uint64(legal.Len()))
+		if dec.err == nil && arity != legal.Len() {
+			dec.fail()
+		}
+
+		var hand MoveSet
+		switch n := dec.size(uint64(arity)); {
+		case dec.err != nil || n == 0:
+			hand = noMoves
+		case n == arity:
+			hand = legal
+		default:
+			list := make(moveList, n)
+			for j := range list {
+				list[j] = move(dec.uvarint())
+			}
+			hand = list
+		}
+
+		var node *Node
+		if i == 0 {
+			node = NewRoot(state, conf)
+			node.hand = hand
+		} else {
+			node = NewNode(up, edge, state, hand, conf)
+			node.count.add(idle, 1)
+		}
+		node.arity = arity
+
+		node.proven = dec.bool()
+		node.best.solved = dec.bool()
+		node.solved, node.exact = dec.float(), dec.float()
+		for _, stat := range []*atomicFloat{
+			&node.value, &node.mean, &node.visits, &node.variance, &node.worst,
+		} {
+			stat.store(dec.float())
+		}
+		node.ε, node.best.score = dec.float(), dec.float()
+		node.top.store(node.best.score)
+
+		if i == 0 {
+			n := dec.uvarint()
+
+			replayed := state.Clone()
+			for j := uint64(0); j < n && dec.err == nil; j++ {
+				list := replayed.Moves().List()
+				k := dec.uvarint()
+				if k >= uint64(len(list)) {
+					dec.fail()
+					break
+				}
+				node.best.moves = append(node.best.moves, list[k])
+				replayed = replayed.Play(list[k])
+			}
+		}
+
+		n := dec.size(uint64(arity))
+		if dec.err != nil {
+			break
+		}
+
+		down, via := make([]uint64, n), make([]Move, n)
+		for j := range down {
+			down[j], via[j] = dec.uvarint(), move(dec.uvarint())
+			if down[j] > i {
+				edges[[2]uint64{i, down[j]}] = via[j]
+			}
+		}
+
+		nodes, downs, vias = append(nodes, node), append(downs, down), append(vias, via)
+	}
+
+	if dec.err == nil && len(nodes) == 0 {
+		dec.fail()
+	}
+
+	if dec.err != nil {
+		return nil, dec.err
+	}
+
+	// Link nodes to all of their parents and register positions.
+	root := nodes[0]
+	for i, node := range nodes {
+		for j, id := range downs[i] {
+			if id >= count {
+				return nil, dec.fail()
+			}
+
+			child := nodes[id]
+			node.down = append(node.down, child)
+			node.rank.add(child)
+
+			if child.up != node {
+				if node.via == nil {
+					node.via = make(map[*Node]Move)
+				}
+				node.via[child] = vias[i][j]
+				child.links = append(child.links, node)
+			}
+		}
+
+		if h, ok := node.state.(Hasher); ok && root.table != nil && i > 0 {
+			root.table.LoadOrStore(h.Hash(), node)
+		}
+	}
+
+	return root, nil
+}
+
+// Config returns the configuration shared by the nodes of the tree. It may be
+// changed before a search is started, eg. to set a budget on a loaded tree.
+func (n *Node) Config() *Config {
+	return n.conf
+}
+
+// snapshot lists the nodes of the tree grown from the calling node with their
+// primary parents and a copy of their children. Nodes are ordered from the root
+// such that primary parents are listed first. A node not yet listed as a child of
+// its primary parent, eg. during a search, is given another parent.
+func (n *Node) snapshot() (nodes, ups []*Node, downs [][]*Node) {
+	parent := make(map[*Node]*Node)
+	children := make(map[*Node][]*Node)
+
+	seen := []*Node{n}
+	for i := 0; i < len(seen); i++ {
+		node := seen[i]
+
+		node.Lock()
+		down := append([]*Node(nil), node.down...)
+		node.Unlock()
+
+		children[node] = down
+		for _, child := range down {
+			if _, ok := parent[child]; !ok && child != n {
+				seen = append(seen, child)
+			}
+			if child.Up() == node || parent[child] == nil {
+				parent[child] = node
+			}
+		}
+	}
+
+	primaries := make(map[*Node][]*Node)
+	for _, node := range seen {
+		if up := parent[node]; up != nil {
+			primaries[up] = append(primaries[up], node)
+		}
+	}
+
+	nodes = []*Node{n}
+	for i := 0; i < len(nodes); i++ {
+		nodes = append(nodes, primaries[nodes[i]]...)
+	}
+
+	ups, downs = make([]*Node, len(nodes)), make([][]*Node, len(nodes))
+	for i, node := range nodes {
+		ups[i], downs[i] = parent[node], children[node]
+	}
+
+	return nodes, ups, downs
+}
+
+// moveIndex locates a move in a list of legal moves.
+func moveIndex(moves []Move, move Move, err *error) uint64 {
+	for i, m := range moves {
+		if m.String() == move.String() {
+			return uint64(i)
+		}
+	}
+
+	if *err == nil {
+		*err = fmt.Errorf("mcs: move %v not found", move)
+	}
+	return 0
+}
+
+// encoder writes varints and floats, the first error sticks.
+type encoder struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func newEncoder(w io.Writer) *encoder {
+	return &encoder{w: bufio.NewWriter(w)}
+}
+
+func (e *encoder) write(p []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(p)
+	}
+}
+
+func (e *encoder) uvarint(x uint64) {
+	e.write(e.buf[:binary.PutUvarint(e.buf[:], x)])
+}
+
+func (e *encoder) varint(x int64) {
+	e.write(e.buf[:binary.PutVarint(e.buf[:], x)])
+}
+
+func (e *encoder) uint64(x uint64) {
+	binary.LittleEndian.PutUint64(e.buf[:8], x)
+	e.write(e.buf[:8])
+}
+
+func (e *encoder) float(x float64) {
+	e.uint64(math.Float64bits(x))
+}
+
+func (e *encoder) bool(b bool) {
+	if b {
+		e.uvarint(1)
+	} else {
+		e.uvarint(0)
+	}
+}
+
+func (e *encoder) string(s string) {
+	e.uvarint(uint64(len(s)))
+	e.write([]byte(s))
+}
+
+func (e *encoder) config(conf *Config) {
+	e.string(conf.UCB.String())
+	e.float(conf.Epsilon)
+	e.float(conf.C)
+	e.float(conf.W)
+	e.float(conf.VisitThreshold)
+	e.bool(conf.Transpositions)
+	e.varint(conf.Seed)
+	e.varint(conf.Budget.Playouts)
+	e.varint(int64(conf.Budget.Nodes))
+}
+
+func (e *encoder) flush() error {
+	if e.err == nil {
+		e.err = e.w.Flush()
+	}
+	return e.err
+}
+
+// decoder reads what an encoder writes, the first error sticks: values read
+// afterwards are zeros.
+type decoder struct {
+	r      *bufio.Reader
+	buf    [8]byte
+	err    error
+	length int64 // of the input, -1 if unknown
+	read   int64
+}
+
+func newDecoder(r io.Reader) *decoder {
+	return &decoder{r: bufio.NewReader(r), length: inputSize(r)}
+}
+
+// inputSize returns the number of bytes left to read from r, -1 if it can't tell.
+func inputSize(r io.Reader) int64 {
+	switch r := r.(type) {
+	case interface{ Len() int }: // eg. bytes.Reader, bytes.Buffer
+		return int64(r.Len())
+	case io.Seeker: // eg. os.File
+		cur, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		end, err := r.Seek(0, io.SeekEnd)
+		if err != nil {
+			return -1
+		}
+		if _, err := r.Seek(cur, io.SeekStart); err != nil {
+			return -1
+		}
+		return end - cur
+	}
+	return -1
+}
+
+// left returns the number of bytes left to read, -1 if unknown.
+func (d *decoder) left() int64 {
+	if d.length < 0 {
+		return -1
+	}
+	return d.length - d.read
+}
+
+// ReadByte is part of io.ByteReader, read bytes are counted.
+func (d *decoder) ReadByte() (byte, error) {
+	b, err := d.r.ReadByte()
+	if err == nil {
+		d.read++
+	}
+	return b, err
+}
+
+func (d *decoder) readFull(p []byte) {
+	n, err := io.ReadFull(d.r, p)
+	d.read += int64(n)
+	d.check(err)
+}
+
+// size reads a count of items, a count larger than max is malformed.
+func (d *decoder) size(max uint64) int {
+	n := d.uvarint()
+	if d.err == nil && n > max {
+		d.fail()
+	}
+	if d.err != nil {
+		return 0
+	}
+	return int(n)
+}
+
+// fail records a malformed input.
+func (d *decoder) fail() error {
+	if d.err == nil {
+		d.err = errors.New("mcs: malformed tree file")
+	}
+	return d.err
+}
+
+func (d *decoder) check(err error) {
+	if d.err == nil && err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		d.err = err
+	}
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	x, err := binary.ReadUvarint(d)
+	d.check(err)
+	return x
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	x, err := binary.ReadVarint(d)
+	d.check(err)
+	return x
+}
+
+func (d *decoder) uint64() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	d.readFull(d.buf[:])
+	return binary.LittleEndian.Uint64(d.buf[:])
+}
+
+func (d *decoder) float() float64 {
+	return math.Float64frombits(d.uint64())
+}
+
+func (d *decoder) bool() bool {
+	return d.uvarint() != 0
+}
+
+func (d *decoder) string() string {
+	n := d.uvarint()
+	if d.err != nil || n > 1<<10 {
+		d.fail()
+		return ""
+	}
+
+	p := make([]byte, n)
+	d.readFull(p)
+	return string(p)
+}
+
+func (d *decoder) config() *Config {
+	conf := &Config{}
+
+	name := d.string()
+	for _, ucb := range formulas {
+		if ucb.String() == name {
+			conf.UCB = ucb
+		}
+	}
+	if conf.UCB == nil && d.err == nil {
+		d.err = fmt.Errorf("mcs: unknown UCB formula %q", name)
+	}
+
+	conf.Epsilon = d.float()
+	conf.C = d.float()
+	conf.W = d.float()
+	conf.VisitThreshold = d.float()
+	conf.Transpositions = d.bool()
+	conf.Seed = d.varint()
+	conf.Budget.Playouts = d.varint()
+	conf.Budget.Nodes = int(d.varint())
+
+	return conf
+}
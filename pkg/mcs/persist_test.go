@@ -0,0 +1,186 @@
+package mcs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+)
+
+func TestSaveTree(t *testing.T) {
+	game := newToy(6, 1, 5, 2, 4, 3)
+
+	for _, transpositions := range []bool{true, false} {
+		conf := NewConfig(0.03, 40, 0)
+		conf.Transpositions = transpositions
+		conf.Seed = 1
+		conf.Budget = Budget{Playouts: 300}
+
+		root := NewRoot(game.Clone(), conf)
+		ConfidentSearch(context.Background(), root, []GamePolicy{nil})
+
+		var saved bytes.Buffer
+		if err := SaveTree(&saved, root); err != nil {
+			t.Fatal(err)
+		}
+		file := saved.Bytes()
+
+		loaded, err := LoadTree(bytes.NewReader(file), game.Clone())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// A loaded tree is saved identically.
+		var resaved bytes.Buffer
+		if err := SaveTree(&resaved, loaded); err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(file, resaved.Bytes()) {
+			t.Errorf("save: transpositions %v, loaded tree differs", transpositions)
+		}
+
+		if n, m := len(root.subtree()), len(loaded.subtree()); n != m {
+			t.Errorf("save: transpositions %v, expected %d nodes, got %d", transpositions, n, m)
+		}
+
+		if got := *loaded.Config(); got.UCB.String() != conf.UCB.String() ||
+			got.C != conf.C || got.Seed != conf.Seed || got.Budget != conf.Budget {
+			t.Errorf("save: configuration %+v loaded as %+v", *conf, got)
+		}
+
+		if best := loaded.Best(); best.String() != root.Best().String() {
+			t.Errorf("save: best decision %v loaded as %v", root.Best(), best)
+		}
+
+		if transpositions && loaded.table.Len() != root.table.Len() {
+			t.Errorf("save: expected %d positions, got %d", root.table.Len(), loaded.table.Len())
+		}
+
+		// The search resumes from the loaded tree.
+		visits := loaded.Visits()
+		loaded.Config().Budget = Budget{Playouts: 200}
+
+		result, _, _ := ConcurrentSearch(context.Background(), loaded, []GamePolicy{nil})
+		if v := loaded.Visits(); v != visits+200 {
+			t.Errorf("save: expected %g visits after resuming, got %g", visits+200, v)
+		}
+
+		score, err := replay(game, result)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if score != result.Score() {
+			t.Errorf("save: replayed %g, expected %g", score, result.Score())
+		}
+	}
+}
+
+func TestLoadTree_errors(t *testing.T) {
+	game := newToy(4, 1, 3, 2)
+
+	root := NewRoot(game.Clone(), NewConfig(0.03, 40, 0))
+	GrowTree(root)
+
+	var saved bytes.Buffer
+	if err := SaveTree(&saved, root); err != nil {
+		t.Fatal(err)
+	}
+	file := saved.Bytes()
+
+	if _, err := LoadTree(bytes.NewReader([]byte("not a tree")), game.Clone()); err == nil {
+		t.Errorf("load: garbage loaded")
+	}
+
+	if _, err := LoadTree(bytes.NewReader(file[:len(file)-1]), game.Clone()); err == nil {
+		t.Errorf("load: truncated file loaded")
+	}
+
+	if _, err := LoadTree(bytes.NewReader(file), newToy(4, 1, 3)); err == nil {
+		t.Errorf("load: tree loaded from another position")
+	}
+
+	if _, err := LoadTree(bytes.NewReader(file), game.Clone()); err != nil {
+		t.Errorf("load: %v", err)
+	}
+
+	// Formulas are saved by name: only those of the package are known.
+	conf := NewConfig(0.03, 40, 0)
+	conf.UCB = func(n *Node) float64 { return UCB1(n) }
+	if err := SaveTree(&bytes.Buffer{}, NewRoot(game.Clone(), conf)); err == nil {
+		t.Errorf("save: unknown formula saved")
+	}
+
+	var unnamed bytes.Buffer
+	enc := newEncoder(&unnamed)
+	enc.string(treeMagic)
+	enc.uvarint(treeVersion)
+	enc.config(&Config{})
+	if err := enc.flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadTree(bytes.NewReader(unnamed.Bytes()), game.Clone()); err == nil {
+		t.Errorf("load: tree without formula loaded")
+	}
+}
+
+func TestLoadTree_crafted(t *testing.T) {
+	game := newToy(4, 1, 3, 2)
+	legal := uint64(game.Moves().Len())
+
+	// craft writes a file of a single root node with the given sizes.
+	craft := func(count, arity, hand, children uint64) []byte {
+		var file bytes.Buffer
+		enc := newEncoder(&file)
+
+		enc.string(treeMagic)
+		enc.uvarint(treeVersion)
+		enc.config(NewConfig(0.03, 40, 0))
+		enc.bool(true)
+		enc.uint64(game.Hash())
+
+		enc.uvarint(count)
+		enc.uvarint(arity)
+		enc.uvarint(hand)
+		enc.bool(false)
+		enc.bool(false)
+		for i := 0; i < 9; i++ {
+			enc.float(0)
+		}
+		enc.uvarint(0) // best moves
+		enc.uvarint(children)
+
+		if err := enc.flush(); err != nil {
+			t.Fatal(err)
+		}
+		return file.Bytes()
+	}
+
+	huge := uint64(1) << 62
+	for _, test := range []struct {
+		name                         string
+		count, arity, hand, children uint64
+	}{
+		{"nodes", huge, legal, legal, 0},
+		{"arity", 1, huge, 0, 0},
+		{"wrong arity", 1, legal + 1, 0, 0},
+		{"hand", 1, legal, huge, 0},
+		{"children", 1, legal, legal, huge},
+		{"more children than moves", 1, legal, legal, legal + 1},
+	} {
+		file := craft(test.count, test.arity, test.hand, test.children)
+
+		// Sizes are bounded whether the reader tells its length or not.
+		for _, r := range []io.Reader{bytes.NewReader(file), struct{ io.Reader }{bytes.NewReader(file)}} {
+			if _, err := LoadTree(r, game.Clone()); err == nil {
+				t.Errorf("load: %s: malformed file loaded", test.name)
+			}
+		}
+	}
+
+	if _, err := LoadTree(bytes.NewReader(craft(1, legal, legal, 0)), game.Clone()); err != nil {
+		t.Errorf("load: crafted root: %v", err)
+	}
+}
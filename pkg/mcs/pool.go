@@ -0,0 +1,165 @@
+// Copyright 2018 Erik Adelbert. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements the goroutines pools of the CMCT pipeline and their
+// adaptive balancing, see Workers.
+
+package mcs
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// pool runs the goroutines of a pipeline stage. A goroutine is retired by sending
+// on the retire channel of its pool.
+type pool struct {
+	run    func(rng *rand.Rand, retire <-chan struct{})
+	seeds  *rand.Rand // nil for goroutines without random choices
+	retire chan struct{}
+
+	stage   sync.WaitGroup // goroutines of the pool
+	stopped *sync.WaitGroup
+
+	size int64 // atomic
+}
+
+func newPool(seeds *rand.Rand, stopped *sync.WaitGroup, run func(*rand.Rand, <-chan struct{})) *pool {
+	return &pool{
+		run:     run,
+		seeds:   seeds,
+		retire:  make(chan struct{}),
+		stopped: stopped,
+	}
+}
+
+// spawn launches count more goroutines. Spawns are sequential: seeds are not
+// safe for concurrent use.
+func (p *pool) spawn(count int) {
+	var rngs []*rand.Rand
+	if p.seeds != nil {
+		rngs = newRands(p.seeds, count)
+	}
+
+	p.stage.Add(count)
+	p.stopped.Add(count)
+	atomic.AddInt64(&p.size, int64(count))
+
+	for i := 0; i < count; i++ {
+		var rng *rand.Rand
+		if rngs != nil {
+			rng = rngs[i]
+		}
+
+		go func() {
+			p.run(rng, p.retire)
+
+			atomic.AddInt64(&p.size, -1)
+			p.stage.Done()
+			p.stopped.Done()
+		}()
+	}
+}
+
+// len returns the number of running goroutines.
+func (p *pool) len() int {
+	return int(atomic.LoadInt64(&p.size))
+}
+
+// hold keeps the pool open until released: wait doesn't return meanwhile, even
+// if all the goroutines of the pool are gone.
+func (p *pool) hold() {
+	p.stage.Add(1)
+}
+
+func (p *pool) release() {
+	p.stage.Done()
+}
+
+// wait returns once all the goroutines of the pool are gone.
+func (p *pool) wait() {
+	p.stage.Wait()
+}
+
+const (
+	balancePeriod  = 50 * time.Millisecond // between two moves
+	balanceSamples = 10                    // fill levels averaged over a period
+
+	highFill = 0.75
+	lowFill  = 0.25
+)
+
+// balance moves goroutines between pools until done. The fill levels of the
+// channels are sampled and averaged over a balancePeriod, then a goroutine of the
+// stage found idle is retired and another one is spawned in the lagging stage.
+// Every pool must be held open while balanced.
+func balance(done <-chan struct{}, pools *[numStages]*pool, positions, outcomes chan job) {
+	ticker := time.NewTicker(balancePeriod / balanceSamples)
+	defer ticker.Stop()
+
+	var p, o float64
+
+	for i := 1; ; i++ {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		p += fill(positions)
+		o += fill(outcomes)
+
+		if i%balanceSamples != 0 {
+			continue
+		}
+
+		from, to, move := rebalance(p/balanceSamples, o/balanceSamples)
+		p, o = 0, 0
+
+		if !move || pools[from].len() <= 1 {
+			continue
+		}
+
+		select {
+		case <-done:
+			return
+		case pools[from].retire <- struct{}{}:
+		}
+
+		pools[to].spawn(1)
+	}
+}
+
+// rebalance tells which stage should give a goroutine to which other given the
+// average fill levels of the positions and outcomes channels.
+func rebalance(positions, outcomes float64) (from, to stage, move bool) {
+	switch {
+	case outcomes >= highFill: // updaters lag behind
+		if positions >= highFill {
+			return walking, updating, true
+		}
+		return sampling, updating, true
+
+	case positions >= highFill: // samplers lag behind
+		if outcomes <= lowFill {
+			return updating, sampling, true
+		}
+		return walking, sampling, true
+
+	case positions <= lowFill: // walkers lag behind
+		return sampling, walking, true
+	}
+
+	return 0, 0, false
+}
+
+// fill returns the fill level of a channel.
+func fill(ch chan job) float64 {
+	if cap(ch) == 0 {
+		return 0
+	}
+	return float64(len(ch)) / float64(cap(ch))
+}
@@ -0,0 +1,85 @@
+package mcs
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWorkers(t *testing.T) {
+	items := make([]int, 20)
+	for i := range items {
+		items[i] = i
+	}
+	game := newToy(items...)
+
+	for _, workers := range []Workers{
+		{Walkers: 1, Samplers: 1, Updaters: 1},
+		{Walkers: 3, Samplers: 1, Updaters: 2, Adaptive: true},
+	} {
+		conf := NewConfig(0.03, 40, 0)
+		conf.Workers = workers
+
+		root := NewRoot(game.Clone(), conf)
+
+		sampled := make(chan []Metrics)
+		go func() {
+			time.Sleep(300 * time.Millisecond)
+			sampled <- RunningMetrics()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		result, stats, _ := ConcurrentSearch(ctx, root, []GamePolicy{nil})
+		cancel()
+
+		if stats.Playouts == 0 {
+			t.Errorf("cmct: %v, no playouts", workers)
+		}
+
+		score, err := replay(game, result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if score != result.Score() {
+			t.Errorf("cmct: %v, replayed %g, expected %g", workers, score, result.Score())
+		}
+
+		metrics := <-sampled
+		if len(metrics) != 1 || metrics[0].Workers == nil {
+			t.Fatalf("cmct: %v, workers not published", workers)
+		}
+		w := *metrics[0].Workers
+
+		total := w.Walkers + w.Samplers + w.Updaters
+		if expected := workers.Walkers + workers.Samplers + workers.Updaters; total != expected && total != expected-1 {
+			t.Errorf("cmct: %v, %d goroutines running, expected %d", workers, total, expected)
+		}
+
+		if !workers.Adaptive && w != workers {
+			t.Errorf("cmct: expected %v, got %v", workers, w)
+		}
+	}
+}
+
+func TestRebalance(t *testing.T) {
+	tests := []struct {
+		positions, outcomes float64
+		from, to            stage
+		move                bool
+	}{
+		{1, 1, walking, updating, true},
+		{0.5, 0.9, sampling, updating, true},
+		{1, 0, updating, sampling, true},
+		{1, 0.5, walking, sampling, true},
+		{0, 0.5, sampling, walking, true},
+		{0.5, 0.5, 0, 0, false},
+	}
+
+	for _, test := range tests {
+		from, to, move := rebalance(test.positions, test.outcomes)
+		if from != test.from || to != test.to || move != test.move {
+			t.Errorf("rebalance: fill levels %g/%g, expected %v %v->%v, got %v %v->%v",
+				test.positions, test.outcomes, test.move, test.from, test.to, move, from, to)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2018 Erik Adelbert. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements anytime progress reports and work budgets: searches
+// publish every improvement of their best decision while running and stop
+// once their budget is exhausted.
+
+package mcs
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Budget bounds a search by its amount of work instead of wall-clock time, results
+// are then independent of the machine load. Zero values stand for no limit. A time
+// limit is given by the deadline of the search context, the first limit reached
+// stops the search.
+type Budget struct {
+	Playouts int64 // completed simulations
+	Nodes    int   // created nodes, see Stats
+}
+
+// Progress is a snapshot of a running search taken when a better decision
+// has just been found.
+type Progress struct {
+	Decision Decision
+	Elapsed  time.Duration // since the start of the search
+	Playouts int64         // simulations completed so far
+	Nodes    int           // nodes created so far
+}
+
+func (p Progress) String() string {
+	return fmt.Sprintf("%v %d playouts %d nodes: %g", p.Elapsed, p.Playouts, p.Nodes, p.Decision.Score())
+}
+
+// tracker follows a search on behalf of its configuration: progress is reported
+// to the Progress callback, the budget is enforced and statistics are collected.
+// Reports are serialized and their scores strictly increase.
+type tracker struct {
+	spinlock
+
+	report func(Progress)
+	budget Budget
+	cancel context.CancelFunc
+	parent *tracker // enclosing search, if any
+
+	root    *Node
+	queues  []queue
+	workers func() Workers
+	start   time.Time
+	window  window // playouts
+	spawned window // nodes
+
+	best uint64 // float64 bits, written under lock and read atomically
+
+	capacity int64 // nodes, see Config.MaxNodes
+
+	// atomic counters
+	playouts     int64
+	nodes        int64
+	depth        int64
+	oversampling int64
+	size         int64 // nodes in the tree
+	limit        int64 // size of the next pruning, see trim
+	pruned       int64
+	stages       [numStages]int64 // nanoseconds
+	pruning      int32            // a goroutine is pruning the tree
+
+	walks sync.RWMutex // walkers are walking the tree, see enter
+}
+
+// trackerKey is the context key of the tracker of the running search.
+type trackerKey struct{}
+
+// newTracker starts following a search. The returned context is cancelled once
+// the budget is exhausted, it must be released by stop at the end of the search.
+// The search is listed by RunningMetrics until stopped.
+// Searches run within the returned context, eg. by RootParallelSearch, are also
+// accounted for in the returned tracker: its budget bounds them all, their
+// decisions are offered to it and their statistics are summed up in it.
+func newTracker(ctx context.Context, root *Node) (context.Context, *tracker) {
+	parent, _ := ctx.Value(trackerKey{}).(*tracker)
+
+	ctx, cancel := context.WithCancel(ctx)
+	start := time.Now()
+	conf := root.conf
+
+	t := &tracker{
+		report: conf.Progress,
+		budget: conf.Budget,
+		cancel: cancel,
+		parent: parent,
+
+		root:    root,
+		start:   start,
+		window:  window{start: start},
+		spawned: window{start: start},
+
+		best: math.Float64bits(math.Inf(-1)),
+	}
+
+	if conf.MaxNodes > 0 {
+		t.capacity, t.limit = int64(conf.MaxNodes), int64(conf.MaxNodes)
+		t.size = int64(len(root.subtree()))
+	}
+	t.register()
+
+	return context.WithValue(ctx, trackerKey{}, t), t
+}
+
+// stop releases the context of the search.
+func (t *tracker) stop() {
+	t.cancel()
+	t.unregister()
+}
+
+// exhausted reports whether the search has used up its budget.
+func (t *tracker) exhausted() bool {
+	if limit := t.budget.Playouts; limit > 0 && atomic.LoadInt64(&t.playouts) >= limit {
+		return true
+	}
+
+	if limit := t.budget.Nodes; limit > 0 && atomic.LoadInt64(&t.nodes) >= int64(limit) {
+		return true
+	}
+
+	return false
+}
+
+// offer reports a complete decision from the root when it improves on the
+// best one seen so far.
+func (t *tracker) offer(decision Decision) {
+	if t.parent != nil {
+		t.parent.offer(decision)
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	{
+		if decision.score <= math.Float64frombits(t.best) {
+			return
+		}
+		atomic.StoreUint64(&t.best, math.Float64bits(decision.score))
+
+		if t.report != nil {
+			t.report(Progress{
+				Decision: decision.Clone(),
+				Elapsed:  time.Since(t.start),
+				Playouts: atomic.LoadInt64(&t.playouts),
+				Nodes:    int(atomic.LoadInt64(&t.nodes)),
+			})
+		}
+	}
+}
+
+// reserve books the creation of a node. It returns false when the node would
+// exceed the nodes budget, it is then not to be created. A reserved node is
+// counted by created once created or given back by release, eg. when an existing
+// node is linked instead.
+func (t *tracker) reserve() bool {
+	if t.parent != nil && !t.parent.reserve() {
+		return false
+	}
+
+	for {
+		n := atomic.LoadInt64(&t.nodes)
+		if limit := t.budget.Nodes; limit > 0 && n >= int64(limit) {
+			if t.parent != nil {
+				t.parent.release()
+			}
+			return false
+		}
+
+		if atomic.CompareAndSwapInt64(&t.nodes, n, n+1) {
+			return true
+		}
+	}
+}
+
+// release gives back a node reserved but not created.
+func (t *tracker) release() {
+	atomic.AddInt64(&t.nodes, -1)
+
+	if t.parent != nil {
+		t.parent.release()
+	}
+}
+
+// playout counts a completed simulation. It returns false when the simulation
+// exceeds the playouts budget, its outcome is then to be discarded. The search
+// context is cancelled as soon as the budget is exhausted.
+func (t *tracker) playout() bool {
+	if t.parent != nil && !t.parent.playout() {
+		return false
+	}
+
+	for {
+		n := atomic.LoadInt64(&t.playouts)
+		if limit := t.budget.Playouts; limit > 0 && n >= limit {
+			return false
+		}
+
+		if atomic.CompareAndSwapInt64(&t.playouts, n, n+1) {
+			break
+		}
+	}
+	t.window.add(time.Now())
+
+	if t.exhausted() {
+		t.cancel()
+	}
+
+	return true
+}
@@ -0,0 +1,135 @@
+package mcs
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProgress(t *testing.T) {
+	game := newToy(1, 2, 3, 4, 5, 6, 7, 8)
+
+	searches := []Search{
+		ConcurrentSearch,
+		ConfidentSearch,
+		NestedSearch,
+		AdaptiveSearch,
+		RootParallelSearch,
+		LeafParallelSearch,
+	}
+
+	for _, search := range searches {
+		var mu sync.Mutex
+		var reports []Progress
+
+		conf := NewConfig(0.03, 40, 0)
+		conf.Progress = func(p Progress) {
+			mu.Lock()
+			reports = append(reports, p)
+			mu.Unlock()
+		}
+
+		root := NewRoot(game.Clone(), conf)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		result, _, _ := search(ctx, root, []GamePolicy{nil})
+		cancel()
+
+		mu.Lock()
+		if len(reports) == 0 {
+			t.Errorf("%v: no progress reported", search)
+		}
+
+		for i, p := range reports {
+			score, err := replay(game, p.Decision)
+			if err != nil {
+				t.Errorf("%v: report #%d: %v", search, i, err)
+			} else if score != p.Decision.Score() {
+				t.Errorf("%v: report #%d: replayed %g, expected %g", search, i, score, p.Decision.Score())
+			}
+
+			if p.Playouts == 0 {
+				t.Errorf("%v: report #%d: no playouts", search, i)
+			}
+
+			if i == 0 {
+				continue
+			}
+
+			prev := reports[i-1]
+			if p.Decision.Score() <= prev.Decision.Score() || p.Elapsed < prev.Elapsed || p.Playouts < prev.Playouts {
+				t.Errorf("%v: report #%d does not improve: %v after %v", search, i, p, prev)
+			}
+		}
+
+		if n := len(reports); n > 0 && reports[n-1].Decision.Score() > result.Score() {
+			t.Errorf("%v: reported %g, returned %g", search, reports[n-1].Decision.Score(), result.Score())
+		}
+		mu.Unlock()
+	}
+}
+
+func TestBudget_playouts(t *testing.T) {
+	items := make([]int, 30)
+	for i := range items {
+		items[i] = i
+	}
+	game := newToy(items...)
+
+	for _, search := range []Search{ConcurrentSearch, ConfidentSearch} {
+		var playouts int64
+
+		conf := NewConfig(0.03, 40, 0)
+		conf.Budget = Budget{Playouts: 500}
+		conf.Progress = func(p Progress) {
+			playouts = p.Playouts
+		}
+
+		root := NewRoot(game.Clone(), conf)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		search(ctx, root, []GamePolicy{nil})
+		if ctx.Err() != nil {
+			t.Errorf("%v: budget not enforced", search)
+		}
+		cancel()
+
+		if visits := root.Visits(); visits != 500 || playouts > 500 {
+			t.Errorf("%v: expected 500 playouts, got %g (%d reported)", search, visits, playouts)
+		}
+	}
+}
+
+func TestBudget_nodes(t *testing.T) {
+	items := make([]int, 30)
+	for i := range items {
+		items[i] = i
+	}
+	game := newToy(items...)
+
+	for _, search := range []Search{ConfidentSearch, LeafParallelSearchWidth(2), ConcurrentSearch} {
+		conf := NewConfig(0.03, 40, 0)
+		conf.VisitThreshold = 0
+		conf.Budget = Budget{Nodes: 100}
+
+		root := GrowTree(NewRoot(game.Clone(), conf))
+		grown := len(root.subtree())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		_, stats, _ := search(ctx, root, []GamePolicy{nil})
+		if ctx.Err() != nil {
+			t.Errorf("%v: budget not enforced", search)
+		}
+		cancel()
+
+		if stats.Nodes != 100 {
+			t.Errorf("%v: expected 100 nodes, got %d", search, stats.Nodes)
+		}
+
+		// Walkers stop expanding once the budget is used up.
+		if n := len(root.subtree()) - grown; n != 100 {
+			t.Errorf("%v: expected 100 nodes in the tree, got %d", search, n)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2018 Erik Adelbert. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements memory-bounded trees: once a tree reaches its capacity,
+// low-visit subtrees are pruned and their moves are given back to the hands of
+// their parents, see Config.MaxNodes.
+
+package mcs
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// pruneRatio is the share of its capacity a tree is pruned down to. The slack
+// spares a full pruning at every expansion.
+const pruneRatio = 0.9
+
+// trim prunes the tree of the search once it has reached its capacity. It is cheap
+// as long as the tree is small. Goroutines of a search may call it concurrently, a
+// single one prunes at a time.
+func (t *tracker) trim() {
+	if t.capacity == 0 {
+		return
+	}
+
+	size := atomic.LoadInt64(&t.size)
+	if size < atomic.LoadInt64(&t.limit) || !atomic.CompareAndSwapInt32(&t.pruning, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&t.pruning, 0)
+
+	t.walks.Lock()
+	defer t.walks.Unlock()
+
+	target := int64(float64(t.capacity) * pruneRatio)
+	pruned := int64(t.root.prune(int(size - target)))
+
+	atomic.AddInt64(&t.pruned, pruned)
+	size = atomic.AddInt64(&t.size, -pruned)
+
+	// Busy or shared subtrees may be left: the tree is then allowed to grow by
+	// the slack again before the next pruning.
+	limit := size + t.capacity - target
+	if limit < t.capacity {
+		limit = t.capacity
+	}
+	atomic.StoreInt64(&t.limit, limit)
+}
+
+// enter keeps the tree from being pruned until the walker of a concurrent search
+// leaves it. Walkers stay in from the root down to the node they send to simulation:
+// once out, their path is spared by the status of that node, with or without
+// virtual loss. It's a no-op when the tree is not bounded.
+func (t *tracker) enter() {
+	if t.capacity > 0 {
+		t.walks.RLock()
+	}
+}
+
+// leave ends the walk started by enter.
+func (t *tracker) leave() {
+	if t.capacity > 0 {
+		t.walks.RUnlock()
+	}
+}
+
+// pruning describes a node of a tree considered for pruning.
+type pruning struct {
+	node   *Node
+	parent *Node
+	visits float64
+	depth  int
+	kept   bool // busy, proven, shared or part of the principal variation
+}
+
+// prune removes at least count nodes from the tree of the calling root if it can,
+// lowest visits subtrees first. It returns the number of nodes removed.
+// A pruned subtree is unlinked from its parent and its move returns to the hand
+// of the parent: it's expanded anew if selected again. Its positions are removed
+// from the transposition table. Subtrees are spared when any of their nodes is
+// - on the principal variation: the statistics of the best decision are kept,
+// - being searched: walked, simulated or under virtual loss,
+// - proven,
+// - transposed: linked to another parent or to a child of another parent.
+// The last child of a node is never pruned.
+func (n *Node) prune(count int) int {
+	if count <= 0 {
+		return 0
+	}
+
+	// Nodes of the principal variation.
+	pv := map[*Node]bool{n: true}
+	node := n
+	for _, move := range n.Best().moves {
+		var next *Node
+		for _, child := range node.Down() {
+			if node.edgeTo(child).String() == move.String() {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+
+		pv[next] = true
+		node = next
+	}
+
+	// Primary subtrees in breadth first order, parents before children.
+	nodes := []*pruning{{node: n, kept: true}}
+	index := map[*Node]int{n: 0}
+
+	for i := 0; i < len(nodes); i++ {
+		parent := nodes[i].node
+
+		var primary bool
+		for _, child := range parent.Down() {
+			if _, seen := index[child]; seen {
+				continue
+			}
+
+			p := pruning{node: child, parent: parent}
+
+			child.Lock()
+			{
+				p.visits, p.depth = child.visits.load(), child.depth
+				p.kept = pv[child] || atomic.LoadInt32(&child.status) != int32(idle) ||
+					atomic.LoadInt32(&child.pending) > 0 || child.proven ||
+					len(child.links) > 0 || len(child.via) > 0
+				primary = child.up == parent
+			}
+			child.Unlock()
+
+			if !primary {
+				continue
+			}
+
+			index[child] = len(nodes)
+			nodes = append(nodes, &p)
+		}
+	}
+
+	// Spared nodes spare their ancestors.
+	for i := len(nodes) - 1; i > 0; i-- {
+		if p := nodes[i]; p.kept {
+			nodes[index[p.parent]].kept = true
+		}
+	}
+
+	candidates := make([]*pruning, 0, len(nodes))
+	for _, p := range nodes {
+		if !p.kept {
+			candidates = append(candidates, p)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].visits != candidates[j].visits {
+			return candidates[i].visits < candidates[j].visits
+		}
+		return candidates[i].depth < candidates[j].depth
+	})
+
+	removed := make(map[*Node]bool)
+	pruned := 0
+
+	for _, p := range candidates {
+		if pruned >= count {
+			break
+		}
+
+		if removed[p.node] || !p.parent.unlink(p.node) {
+			continue
+		}
+
+		// The whole primary subtree goes, nodes pruned before included.
+		subtree := []*Node{p.node}
+		for i := 0; i < len(subtree); i++ {
+			node := subtree[i]
+			if !removed[node] {
+				removed[node] = true
+				pruned++
+				n.count.add(node.Status(), -1)
+			}
+
+			for _, child := range node.Down() {
+				if nodes[index[child]].parent == node {
+					subtree = append(subtree, child)
+				}
+			}
+
+			if n.table != nil {
+				n.table.Delete(node.state.(Hasher).Hash(), node)
+			}
+		}
+	}
+
+	return pruned
+}
+
+// unlink removes a child from the calling node and gives its move back to the hand
+// of the node, unless the child is the last one left or has been proven meanwhile.
+// Pruned children no longer notify the calling node of their proofs.
+func (n *Node) unlink(child *Node) bool {
+	n.Lock()
+	defer n.Unlock()
+	{
+		if len(n.down) <= 1 {
+			return false
+		}
+
+		child.Lock()
+		defer child.Unlock()
+		{
+			if child.proven {
+				return false
+			}
+
+			for i, node := range n.down {
+				if node == child {
+					n.down = append(n.down[:i:i], n.down[i+1:]...)
+					n.rank.remove(child)
+					break
+				}
+			}
+			hand := append([]Move(nil), n.hand.List()...)
+			n.hand = moveList(append(hand, child.edge))
+
+			child.pruned = true
+		}
+	}
+
+	return true
+}
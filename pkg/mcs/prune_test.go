@@ -0,0 +1,196 @@
+package mcs
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPrune(t *testing.T) {
+	items := make([]int, 20)
+	for i := range items {
+		items[i] = i
+	}
+	game := newToy(items...)
+
+	for _, test := range []struct {
+		search         Search
+		concurrent     bool
+		transpositions bool
+	}{
+		{ConfidentSearch, false, false},
+		{LeafParallelSearchWidth(2), false, false},
+		{ConcurrentSearch, true, false},
+		{ConfidentSearch, false, true},
+		{LeafParallelSearchWidth(2), false, true},
+		{ConcurrentSearch, true, true},
+	} {
+		search := test.search
+
+		conf := NewConfig(0.03, 40, 0)
+		conf.VisitThreshold = 0
+		conf.Transpositions = test.transpositions
+		conf.MaxNodes = 200
+		conf.Budget = Budget{Playouts: 3000}
+
+		root := GrowTree(NewRoot(game.Clone(), conf))
+		grown := len(root.subtree())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		result, stats, _ := search(ctx, root, []GamePolicy{nil})
+		cancel()
+
+		if stats.Pruned == 0 {
+			t.Fatalf("%v: tree not pruned, %v", search, stats)
+		}
+
+		size := len(root.subtree())
+
+		// Every node is accounted for, walkers of concurrent searches may expand
+		// the tree past its cap before the next pruning.
+		if limit := conf.MaxNodes; test.concurrent {
+			if size > 2*limit {
+				t.Errorf("%v: %d nodes, expected about %d", search, size, limit)
+			}
+		} else if size > limit {
+			t.Errorf("%v: %d nodes, expected at most %d", search, size, limit)
+		}
+		if n := grown + stats.Nodes - stats.Pruned; size != n {
+			t.Errorf("%v: %d nodes, expected %d", search, size, n)
+		}
+
+		score, err := replay(game, result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if score != result.Score() {
+			t.Errorf("%v: replayed %g, expected %g", search, score, result.Score())
+		}
+
+		// Pruned moves are given back to the hands of their parents.
+		for _, node := range root.subtree() {
+			if n := len(node.Down()) + node.Hand().Len(); n != node.arity {
+				t.Fatalf("%v: %d moves left out of %d", search, n, node.arity)
+			}
+		}
+	}
+}
+
+func TestNode_prune(t *testing.T) {
+	items := make([]int, 20)
+	for i := range items {
+		items[i] = i
+	}
+	game := newToy(items...)
+
+	conf := NewConfig(0.03, 40, 0)
+	conf.VisitThreshold = 0
+	conf.Transpositions = false
+	conf.Budget = Budget{Playouts: 2000}
+
+	root := NewRoot(game.Clone(), conf)
+	ConfidentSearch(context.Background(), root, []GamePolicy{nil})
+
+	// Principal variation within the tree.
+	var pv []*Node
+	visits := make(map[*Node]float64)
+	for node := root; node != nil; {
+		pv = append(pv, node)
+		visits[node] = node.Visits()
+
+		var next *Node
+		if moves := root.Best().moves; len(pv) <= len(moves) {
+			for _, child := range node.Down() {
+				if node.edgeTo(child).String() == moves[len(pv)-1].String() {
+					next = child
+				}
+			}
+		}
+		node = next
+	}
+
+	size := len(root.subtree())
+	pruned := root.prune(size)
+
+	if pruned == 0 || pruned >= size {
+		t.Fatalf("prune: %d nodes pruned out of %d", pruned, size)
+	}
+
+	if n := len(root.subtree()); n != size-pruned {
+		t.Errorf("prune: %d nodes left, expected %d", n, size-pruned)
+	}
+
+	for i, node := range pv[1:] {
+		found := false
+		for _, child := range pv[i].Down() {
+			found = found || child == node
+		}
+		if !found {
+			t.Fatalf("prune: node %d of the principal variation pruned", i+1)
+		}
+
+		if node.Visits() != visits[node] {
+			t.Errorf("prune: node %d of the principal variation visited %g times, expected %g", i+1, node.Visits(), visits[node])
+		}
+	}
+
+	// The tree is searched anew once pruned.
+	conf.Budget = Budget{Playouts: 500}
+	result, _, _ := ConfidentSearch(context.Background(), root, []GamePolicy{nil})
+
+	score, err := replay(game, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score != result.Score() {
+		t.Errorf("prune: replayed %g, expected %g", score, result.Score())
+	}
+}
+
+func TestTracker_trim(t *testing.T) {
+	items := make([]int, 20)
+	for i := range items {
+		items[i] = i
+	}
+	game := newToy(items...)
+
+	conf := NewConfig(0.03, 40, 0)
+	conf.VisitThreshold = 0
+	conf.Budget = Budget{Playouts: 2000}
+
+	root := NewRoot(game.Clone(), conf)
+	ConfidentSearch(context.Background(), root, []GamePolicy{nil})
+	size := len(root.subtree())
+
+	// Without virtual loss, nothing spares the path of a walker until it reaches
+	// a node: the tree is not pruned meanwhile.
+	conf.MaxNodes = size / 2
+	_, track := newTracker(context.Background(), root)
+	defer track.stop()
+
+	track.enter()
+
+	trimmed := make(chan struct{})
+	go func() {
+		track.trim()
+		close(trimmed)
+	}()
+
+	select {
+	case <-trimmed:
+		t.Fatalf("trim: tree pruned while walked")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if n := len(root.subtree()); n != size {
+		t.Errorf("trim: %d nodes left while walked, expected %d", n, size)
+	}
+
+	track.leave()
+	<-trimmed
+
+	if n := len(root.subtree()); n >= size || int64(n) != atomic.LoadInt64(&track.size) {
+		t.Errorf("trim: %d nodes left out of %d, %d counted", n, size, atomic.LoadInt64(&track.size))
+	}
+}
@@ -0,0 +1,22 @@
+// Copyright 2018 Erik Adelbert. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mcs
+
+import "math/rand"
+
+// newRand returns a random generator seeded with seed. Generators are not safe
+// for concurrent use: every goroutine of a search owns one.
+func newRand(seed int64) *rand.Rand {
+	return rand.New(rand.NewSource(seed))
+}
+
+// newRands derives count independent generators from seeds.
+func newRands(seeds *rand.Rand, count int) []*rand.Rand {
+	rngs := make([]*rand.Rand, count)
+	for i := range rngs {
+		rngs[i] = newRand(seeds.Int63())
+	}
+	return rngs
+}
@@ -0,0 +1,34 @@
+package mcs
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestConfidentSearch_reproducible(t *testing.T) {
+	game := newToy(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12)
+
+	search := func(seed int64) Decision {
+		conf := NewConfig(0.03, 40, 0)
+		conf.Seed = seed
+		conf.Budget = Budget{Playouts: 300}
+
+		root := NewRoot(game.Clone(), conf)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		{
+			result, _, _ := ConfidentSearch(ctx, root, []GamePolicy{nil})
+			return result
+		}
+	}
+
+	for seed := int64(0); seed < 4; seed++ {
+		first, second := search(seed), search(seed)
+
+		if first.String() != second.String() {
+			t.Errorf("uct: seed %d, %v then %v", seed, first, second)
+		}
+	}
+}
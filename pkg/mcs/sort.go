@@ -2,44 +2,157 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// This sort interface is adapted from the SortKeys example of the sort package.
-// see https://golang.org/pkg/sort/
+// This file implements the ranking of children by value used during selection.
+// It is adapted from the PriorityQueue example of the container/heap package.
+// see https://golang.org/pkg/container/heap/
 
 package mcs
 
-import "sort"
+import "container/heap"
 
-// by is the type of a "less" function that defines the ordering of nodes
-// in a Monte-Carlo tree.
-type by func(n1, n2 **Node) bool
+// ranking orders the children of a node by value, best first: selecting the best
+// child costs O(log n) instead of sorting them all. It's a binary max-heap kept in
+// order as values change, see rerank. Children are ranked by their value lowered
+// by virtual loss, if any.
+// A ranking is read and written under the lock of its node. Values are read
+// atomically and may change while the heap is being reordered: the order is
+// restored once the changed children are reranked.
+type ranking struct {
+	nodes []*Node
+	index map[*Node]int
+}
 
-// sortDescending sorts nodes in reverse order.
-func (by by) sortDescending(nodes []*Node) {
-	ns := &nodeSorter{nodes: nodes, by: by}
-	sort.Sort(sort.Reverse(ns))
+// Len is part of heap.Interface.
+func (r *ranking) Len() int {
+	return len(r.nodes)
 }
 
-// value is a "less" function that defines nodes order by their UCB values.
-var value = func(n1, n2 **Node) bool { // Sort by value
-	return (*n1).Value() < (*n2).Value()
+// Less is part of heap.Interface, best nodes first.
+func (r *ranking) Less(i, j int) bool {
+	return r.nodes[i].virtualValue() > r.nodes[j].virtualValue()
 }
 
-type nodeSorter struct {
-	nodes []*Node
-	by    func(n1, n2 **Node) bool
+// Swap is part of heap.Interface.
+func (r *ranking) Swap(i, j int) {
+	r.nodes[i], r.nodes[j] = r.nodes[j], r.nodes[i]
+	r.index[r.nodes[i]], r.index[r.nodes[j]] = i, j
+}
+
+// Push is part of heap.Interface, use add instead.
+func (r *ranking) Push(x interface{}) {
+	node := x.(*Node)
+	if r.index == nil {
+		r.index = make(map[*Node]int)
+	}
+
+	r.index[node] = len(r.nodes)
+	r.nodes = append(r.nodes, node)
+}
+
+// Pop is part of heap.Interface, use remove instead.
+func (r *ranking) Pop() interface{} {
+	last := len(r.nodes) - 1
+	node := r.nodes[last]
+
+	r.nodes[last] = nil
+	r.nodes = r.nodes[:last]
+	delete(r.index, node)
+
+	return node
+}
+
+// add ranks a new child.
+func (r *ranking) add(node *Node) {
+	if _, ok := r.index[node]; !ok {
+		heap.Push(r, node)
+	}
+}
+
+// remove unranks a child, if ranked.
+func (r *ranking) remove(node *Node) {
+	if i, ok := r.index[node]; ok {
+		heap.Remove(r, i)
+	}
+}
+
+// fix moves a child whose value has changed to its rank, if ranked.
+func (r *ranking) fix(node *Node) {
+	if i, ok := r.index[node]; ok {
+		heap.Fix(r, i)
+	}
 }
 
-// Len is part of sort.Interface.
-func (s *nodeSorter) Len() int {
-	return len(s.nodes)
+// first returns the best ranked child accepted by ok, nil if none is. Children are
+// tried in order without reordering the heap: a frontier of the positions of the
+// next best candidates is kept, it only grows with the number of refused children.
+func (r *ranking) first(ok func(*Node) bool) *Node {
+	if len(r.nodes) == 0 {
+		return nil
+	}
+
+	next := &frontier{ranked: r, positions: []int{0}}
+	for next.Len() > 0 {
+		i := heap.Pop(next).(int)
+		if node := r.nodes[i]; ok(node) {
+			return node
+		}
+
+		for _, child := range [...]int{2*i + 1, 2*i + 2} {
+			if child < len(r.nodes) {
+				heap.Push(next, child)
+			}
+		}
+	}
+
+	return nil
+}
+
+// frontier is a max-heap of positions in a ranking, see first.
+type frontier struct {
+	ranked    *ranking
+	positions []int
+}
+
+func (f *frontier) Len() int {
+	return len(f.positions)
+}
+
+func (f *frontier) Less(i, j int) bool {
+	return f.ranked.Less(f.positions[i], f.positions[j])
+}
+
+func (f *frontier) Swap(i, j int) {
+	f.positions[i], f.positions[j] = f.positions[j], f.positions[i]
+}
+
+func (f *frontier) Push(x interface{}) {
+	f.positions = append(f.positions, x.(int))
 }
 
-// Swap is part of sort.Interface.
-func (s *nodeSorter) Swap(i, j int) {
-	s.nodes[i], s.nodes[j] = s.nodes[j], s.nodes[i]
+func (f *frontier) Pop() interface{} {
+	last := len(f.positions) - 1
+	i := f.positions[last]
+	f.positions = f.positions[:last]
+
+	return i
 }
 
-// Less is part of sort.Interface. It is implemented by calling the "by" closure in the sorter.
-func (s *nodeSorter) Less(i, j int) bool {
-	return s.by(&s.nodes[i], &s.nodes[j])
+// rerank moves the calling node to its rank among the children of each of its
+// parents once its value has changed.
+func (n *Node) rerank() {
+	n.Lock()
+	up, links := n.up, n.links
+	n.Unlock()
+
+	if up != nil {
+		up.Lock()
+		up.rank.fix(n)
+		up.Unlock()
+	}
+
+	for _, parent := range links {
+		parent.Lock()
+		parent.rank.fix(n)
+		parent.Unlock()
+	}
 }
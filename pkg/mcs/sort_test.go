@@ -1 +1,50 @@
 package mcs
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestRanking(t *testing.T) {
+	root := GrowTree(NewRoot(newToy(1, 2, 3, 4, 5, 6, 7, 8), NewConfig(0.03, 40, 0)))
+	children := root.Down()
+
+	rng := rand.New(rand.NewSource(1))
+	best := func(ok func(*Node) bool) *Node {
+		var top *Node
+		for _, child := range children {
+			if ok(child) && (top == nil || child.Value() > top.Value()) {
+				top = child
+			}
+		}
+		return top
+	}
+	all := func(*Node) bool { return true }
+
+	for i := 0; i < 100; i++ {
+		children[rng.Intn(len(children))].SetValue(rng.Float64())
+
+		if got, expected := root.rank.first(all), best(all); got.Value() != expected.Value() {
+			t.Fatalf("ranking: best value %g, got %g", expected.Value(), got.Value())
+		}
+
+		// Children are tried in order.
+		threshold := rng.Float64()
+		below := func(node *Node) bool { return node.Value() < threshold }
+		if got, expected := root.rank.first(below), best(below); got != expected {
+			t.Fatalf("ranking: best child below %g is %p, got %p", threshold, expected, got)
+		}
+	}
+
+	none := func(*Node) bool { return false }
+	if node := root.rank.first(none); node != nil {
+		t.Errorf("ranking: no child accepted, got %p", node)
+	}
+
+	for _, child := range children {
+		root.rank.remove(child)
+	}
+	if n := root.rank.Len(); n != 0 {
+		t.Errorf("ranking: %d children left", n)
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2018 Erik Adelbert. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements per search statistics. Every search collects its own
+// figures: concurrent searches don't interfere with each other.
+
+package mcs
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Stats describes the work done by a search. Stages times are cumulated over
+// all the goroutines of a search: they exceed the elapsed time of concurrent
+// searches.
+type Stats struct {
+	Elapsed time.Duration
+
+	Nodes    int     // created nodes, transposed positions are not counted twice
+	Playouts int64   // completed simulations
+	Rate     float64 // playouts per second over the last rateWindow
+	MaxDepth int     // deepest node reached from the root
+	Pruned   int     // nodes removed to bound the tree, see Config.MaxNodes
+
+	// Oversampling counts the selections of a busy child when no child is
+	// available, see Downselect.
+	Oversampling int64
+
+	Walking  time.Duration // selections and expansions
+	Sampling time.Duration // simulations
+	Updating time.Duration // back propagations
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("%d nodes (%d pruned), %d playouts (%.0f/s), depth %d, %d oversampling, walking %v, sampling %v, updating %v",
+		s.Nodes, s.Pruned, s.Playouts, s.Rate, s.MaxDepth, s.Oversampling, s.Walking, s.Sampling, s.Updating)
+}
+
+// merge sums up the statistics of two successive searches. The rate is the rate
+// of the latter search.
+func (s Stats) merge(next Stats) Stats {
+	s.Elapsed += next.Elapsed
+	s.Nodes += next.Nodes
+	s.Playouts += next.Playouts
+	s.Rate = next.Rate
+	if next.MaxDepth > s.MaxDepth {
+		s.MaxDepth = next.MaxDepth
+	}
+	s.Pruned += next.Pruned
+	s.Oversampling += next.Oversampling
+	s.Walking += next.Walking
+	s.Sampling += next.Sampling
+	s.Updating += next.Updating
+
+	return s
+}
+
+// stage is a step of a Monte-Carlo search.
+type stage int
+
+const (
+	walking stage = iota
+	sampling
+	updating
+	numStages
+)
+
+// rateWindow is the span over which the playouts rate is measured. It's divided
+// in one second buckets.
+const rateWindow = 10 * time.Second
+
+// window counts events over the last rateWindow.
+type window struct {
+	spinlock
+
+	start  time.Time
+	counts [rateWindow / time.Second]int64
+	stamps [rateWindow / time.Second]int64 // bucket times, in seconds since start
+}
+
+// add counts an event.
+func (w *window) add(now time.Time) {
+	sec := int64(now.Sub(w.start) / time.Second)
+	i := sec % int64(len(w.counts))
+
+	w.Lock()
+	{
+		if w.stamps[i] != sec {
+			w.stamps[i], w.counts[i] = sec, 0
+		}
+		w.counts[i]++
+	}
+	w.Unlock()
+}
+
+// rate returns the number of events per second over the last rateWindow.
+func (w *window) rate(now time.Time) float64 {
+	elapsed := now.Sub(w.start)
+	sec := int64(elapsed / time.Second)
+
+	var count int64
+
+	w.Lock()
+	{
+		for i, stamp := range w.stamps {
+			if stamp > sec-int64(len(w.counts)) && w.counts[i] > 0 {
+				count += w.counts[i]
+			}
+		}
+	}
+	w.Unlock()
+
+	span := elapsed
+	if full := rateWindow - time.Second + elapsed%time.Second; span > full {
+		span = full
+	}
+
+	if span <= 0 {
+		return 0
+	}
+	return float64(count) / span.Seconds()
+}
+
+// created counts a node created by the search, its creation has been reserved.
+// The search context is cancelled as soon as the budget is exhausted.
+func (t *tracker) created() {
+	atomic.AddInt64(&t.size, 1)
+	t.spawned.add(time.Now())
+
+	if t.parent != nil {
+		t.parent.created()
+	}
+
+	if t.exhausted() {
+		t.cancel()
+	}
+}
+
+// reached records the depth of a node reached by the search.
+func (t *tracker) reached(depth int) {
+	if t.parent != nil {
+		t.parent.reached(depth)
+	}
+
+	for {
+		max := atomic.LoadInt64(&t.depth)
+		if int64(depth) <= max || atomic.CompareAndSwapInt64(&t.depth, max, int64(depth)) {
+			return
+		}
+	}
+}
+
+// oversampled counts a selection of a busy node.
+func (t *tracker) oversampled() {
+	atomic.AddInt64(&t.oversampling, 1)
+
+	if t.parent != nil {
+		t.parent.oversampled()
+	}
+}
+
+// spent records time spent in a stage since start.
+func (t *tracker) spent(s stage, start time.Time) {
+	elapsed := int64(time.Since(start))
+
+	for ; t != nil; t = t.parent {
+		atomic.AddInt64(&t.stages[s], elapsed)
+	}
+}
+
+// stats returns the statistics of the search so far.
+func (t *tracker) stats() Stats {
+	now := time.Now()
+
+	return Stats{
+		Elapsed: now.Sub(t.start),
+
+		Nodes:    int(atomic.LoadInt64(&t.nodes)),
+		Playouts: atomic.LoadInt64(&t.playouts),
+		Rate:     t.window.rate(now),
+		MaxDepth: int(atomic.LoadInt64(&t.depth)),
+		Pruned:   int(atomic.LoadInt64(&t.pruned)),
+
+		Oversampling: atomic.LoadInt64(&t.oversampling),
+
+		Walking:  time.Duration(atomic.LoadInt64(&t.stages[walking])),
+		Sampling: time.Duration(atomic.LoadInt64(&t.stages[sampling])),
+		Updating: time.Duration(atomic.LoadInt64(&t.stages[updating])),
+	}
+}
@@ -0,0 +1,58 @@
+package mcs
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	items := make([]int, 20)
+	for i := range items {
+		items[i] = i
+	}
+	game := newToy(items...)
+
+	for _, search := range []Search{ConcurrentSearch, ConfidentSearch} {
+		conf := NewConfig(0.03, 40, 0)
+		conf.VisitThreshold = 0
+		conf.Budget = Budget{Playouts: 500}
+
+		root := GrowTree(NewRoot(game.Clone(), conf))
+		grown := len(root.subtree())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		_, stats, _ := search(ctx, root, []GamePolicy{nil})
+		cancel()
+
+		if stats.Playouts != 500 {
+			t.Errorf("%v: expected 500 playouts, got %d", search, stats.Playouts)
+		}
+
+		if n := len(root.subtree()) - grown; stats.Nodes != n {
+			t.Errorf("%v: expected %d nodes, got %d", search, n, stats.Nodes)
+		}
+
+		if stats.MaxDepth == 0 || stats.Rate <= 0 {
+			t.Errorf("%v: unexpected %v", search, stats)
+		}
+
+		if stats.Walking <= 0 || stats.Sampling <= 0 || stats.Updating <= 0 {
+			t.Errorf("%v: stages not timed: %v", search, stats)
+		}
+	}
+}
+
+func TestWindow(t *testing.T) {
+	start := time.Now()
+	w := window{start: start}
+
+	for i := 0; i < 3*int(rateWindow/time.Second); i++ {
+		w.add(start.Add(time.Duration(i) * time.Second))
+	}
+
+	now := start.Add(3*rateWindow - time.Second/2)
+	if rate := w.rate(now); rate < 0.9 || rate > 1.1 {
+		t.Errorf("window: expected 1 event per second, got %g", rate)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2018 Erik Adelbert. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file implements a transposition table: identical positions reached through
+// different paths share a node, the tree becomes a DAG.
+// see https://www.researchgate.net/publication/224367785_Transpositions_and_move_groups_in_Monte_Carlo_tree_search
+
+package mcs
+
+// Hasher is implemented by game states which are identified by a hash. Positions
+// sharing a hash are merged in a transposition table. Games are expected not to
+// repeat positions during play and distinct moves from a position are expected
+// to lead to distinct positions.
+type Hasher interface {
+	Hash() uint64
+}
+
+// table maps positions hashes to nodes. It is shared by all the nodes of a tree.
+type table struct {
+	spinlock
+	nodes map[uint64]*Node
+}
+
+// newTable allocates an empty table.
+func newTable() *table {
+	return &table{nodes: make(map[uint64]*Node)}
+}
+
+// Load returns the node registered for the given hash, nil if there's none.
+func (t *table) Load(hash uint64) *Node {
+	t.Lock()
+	defer t.Unlock()
+	{
+		return t.nodes[hash]
+	}
+}
+
+// LoadOrStore returns the node registered for the given hash if any. Otherwise,
+// node is registered and returned.
+func (t *table) LoadOrStore(hash uint64, node *Node) (actual *Node, loaded bool) {
+	t.Lock()
+	defer t.Unlock()
+	{
+		if actual, loaded = t.nodes[hash]; loaded {
+			return
+		}
+		t.nodes[hash] = node
+		return node, false
+	}
+}
+
+// Delete unregisters node if it is the node registered for the given hash.
+func (t *table) Delete(hash uint64, node *Node) {
+	t.Lock()
+	defer t.Unlock()
+	{
+		if t.nodes[hash] == node {
+			delete(t.nodes, hash)
+		}
+	}
+}
+
+// Len returns the number of registered positions.
+func (t *table) Len() int {
+	t.Lock()
+	defer t.Unlock()
+	{
+		return len(t.nodes)
+	}
+}
@@ -0,0 +1,89 @@
+package mcs
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// expand fully grows the tree below n and returns the set of distinct nodes.
+func expand(n *Node, seen map[*Node]bool) map[*Node]bool {
+	if seen[n] {
+		return seen
+	}
+	seen[n] = true
+
+	n.ExpandAll(0)
+	for _, child := range n.Down() {
+		expand(child, seen)
+	}
+	return seen
+}
+
+func TestTable_transpositions(t *testing.T) {
+	game := newToy(4, 1, 3, 2)
+
+	conf := NewConfig(0.03, 40, 0)
+	conf.Transpositions = true
+	dag := expand(NewRoot(game.Clone(), conf), map[*Node]bool{})
+
+	// every subset of items is a distinct position
+	if n := len(dag); n != 16 {
+		t.Errorf("table: expected 16 positions, got %d", n)
+	}
+
+	conf = NewConfig(0.03, 40, 0)
+	tree := expand(NewRoot(game.Clone(), conf), map[*Node]bool{})
+
+	// 1 + 4 + 4*3 + 4*3*2 + 4*3*2*1
+	if n := len(tree); n != 65 {
+		t.Errorf("table: expected 65 nodes, got %d", n)
+	}
+}
+
+func TestTable_solved(t *testing.T) {
+	game := newToy(4, 1, 3, 2)
+
+	conf := NewConfig(0.03, 40, 0)
+	conf.Transpositions = true
+
+	root := NewRoot(game.Clone(), conf)
+	expand(root, map[*Node]bool{})
+
+	if !root.IsSolved() {
+		t.Fatalf("table: expanded dag not solved")
+	}
+
+	result := root.Proof()
+	if best := game.optimum(); result.Score() != best {
+		t.Errorf("table: expected proven optimum %g, got %g", best, result.Score())
+	}
+
+	score, err := replay(game, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if score != result.Score() {
+		t.Errorf("table: replayed %g, expected %g", score, result.Score())
+	}
+}
+
+func TestConcurrentSearch_transpositions(t *testing.T) {
+	game := newToy(5, 1, 4, 2, 3)
+
+	for _, transpositions := range []bool{true, false} {
+		conf := NewConfig(0.03, 40, 0)
+		conf.Transpositions = transpositions
+
+		root := NewRoot(game.Clone(), conf)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		result, _, _ := ConcurrentSearch(ctx, root, []GamePolicy{nil})
+		cancel()
+
+		if !result.Solved() || result.Score() != game.optimum() {
+			t.Errorf("cmct: transpositions %v, expected proven optimum %g, got %v",
+				transpositions, game.optimum(), result)
+		}
+	}
+}
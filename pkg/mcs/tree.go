@@ -10,86 +10,16 @@ package mcs
 
 import (
 	"fmt"
-	"log"
 	"math"
 	"math/rand"
 	"strings"
+	"sync/atomic"
 )
 
-// VisitThreshold is the minimal number of simulations a position has to go through
-// before being registered in the tree as a node.
+// VisitThreshold is the default minimal number of simulations a position has to go
+// through before being registered in the tree as a node.
 const VisitThreshold = 8
 
-var nodeCounter struct {
-	spinlock
-	value int
-}
-
-func nodeCountInc() {
-	nodeCounter.Lock()
-	{
-		nodeCounter.value++
-	}
-	nodeCounter.Unlock()
-}
-
-// NodeCount returns the grand total of created nodes.
-func NodeCount() int {
-	var count int
-	nodeCounter.Lock()
-	{
-		count = nodeCounter.value
-	}
-	nodeCounter.Unlock()
-	return count
-}
-
-func NodeCountReset() {
-	nodeCounter.Lock()
-	{
-		nodeCounter.value = 0
-	}
-	nodeCounter.Unlock()
-}
-
-// NodeRate returns the nodes creation rate on a 10s window.
-//var NodeRate = nodometer()
-/*
-func nodometer() func() float64 {
-	lock := newSpinlock()
-
-	last := 0
-	t0 := time.Now()
-	ticker := time.NewTicker(10 * time.Second)
-
-	go func() {
-		for t := range ticker.C {
-			lock.Lock()
-			{
-				t0 = t
-				last = NodeCount()
-			}
-			lock.Unlock()
-		}
-	}()
-
-	return func() float64 {
-		var count, elapsed float64
-
-		current := NodeCount()
-
-		lock.Lock()
-		{
-			count = float64(current - last)
-			elapsed = float64(time.Since(t0).Seconds())
-		}
-		lock.Unlock()
-
-		return count / elapsed
-	}
-}
-*/
-
 // NodeStatus reflects the cycle of nodes during Monte-Carlo Searches.
 // A node is either up to date (Idle), or went through selection/expansion step (Walked),
 // has been sent to simulation (Sampling) or out of simulation (Sampled). The status is
@@ -122,79 +52,122 @@ func (ns NodeStatus) String() string {
 }
 
 // Node composes a multi-branching tree asymptotically akin to mini-max tree.
+//
+// Nodes are shared by the goroutines of a search, each one is guarded by its own
+// spinlock. Locking rules are:
+// - fields are read and written under the lock of their node, the configuration
+// aside. Exported methods take it themselves and must not be called while holding it,
+// - statistics, value, status and virtual losses are atomic: they're written under
+// the lock, or atomically for the last three, and read without it. Statistics are
+// snapshot through a seqlock, see statistics,
+// - Down returns a copy of the children: the slice is shuffled in place during
+// selection and grown during expansion,
+// - a node may lock its children while locked, never its parents: locks are taken
+// downward only, see downselect and unlink,
+// - the lock is not reentrant and is held for short sections, no channel operation
+// is run under it. Methods suffixed Unsafe expect the caller to hold it.
 type Node struct {
 	*spinlock
 
 	edge   Move
 	depth  int
-	status NodeStatus
+	status int32 // NodeStatus, atomic
 
 	up   *Node
 	down []*Node
+	rank ranking // children ranked by value, see downselect
+
+	links []*Node        // other parents of a transposed node
+	via   map[*Node]Move // edges to children whose primary parent is another node
+	table *table         // transpositions shared by the whole tree
+	count *census        // statuses of the nodes of the whole tree
 
 	hand  MoveSet
 	state GameState
 
-	best Decision
+	best Decision // its score is also top
 
-	solved float64
+	arity  int     // number of legal moves, ie. of children once fully expanded
+	solved float64 // number of solved children
+	proven bool    // exact value is known
+	pruned bool    // removed from the tree, see prune
+	exact  float64 // best score reachable from the position
 
-	value float64
+	value   atomicFloat
+	pending int32       // simulations in progress through the node, see VirtualLoss
+	lowered atomicFloat // value under virtual loss, see devalue
 
-	mean     float64
-	visits   float64
-	variance float64
+	seq      seqlock // statistics below are written under the lock and the seqlock
+	mean     atomicFloat
+	visits   atomicFloat
+	variance atomicFloat
+	worst    atomicFloat
+	top      atomicFloat // score of the best decision
 
-	ε float64
-	c float64
-	w float64
+	conf *Config
+	ε    float64 // entropy, it increases when oversampling
 }
 
 // CloneRoot returns a memory independent copy of the calling node.
 func CloneRoot(root *Node) *Node {
-	initial, ε, c, w := root.State().Clone(), root.ε, root.c, root.w
+	initial, conf := root.State().Clone(), root.conf
 
-	clone := NewRoot(initial, ε, c, w)
+	clone := NewRoot(initial, conf)
 	clone.best = root.Best().Clone()
+	clone.top.store(clone.best.score)
 
 	return clone
 }
 
 // GrowTree expands a root node in order to bootstrap a search.
+// A terminal root is solved from the start, a nil root grows no tree.
 func GrowTree(root *Node) *Node {
-	if root == nil || root.hand.Len() == 0 {
-		// TODO: error  handling
-		panic("no moves")
+	if root == nil {
+		return nil
 	}
 
-	root.ExpandAll(math.Inf(1))
+	if root.Hand().Len() > 0 {
+		root.ExpandAll(math.Inf(1))
+	}
 
 	return root
 }
 
 // NewNode allocates a Monte-Carlo tree node.
-func NewNode(up *Node, edge Move, state GameState, hand MoveSet, ε, c, w float64) *Node {
-	nodeCountInc()
-
+func NewNode(up *Node, edge Move, state GameState, hand MoveSet, conf *Config) *Node {
 	depth := 0
 	if up != nil {
 		depth = up.depth + 1
 	}
 
+	var tt *table
+	var count *census
+	if up != nil {
+		tt, count = up.table, up.count
+	}
+
 	var node = Node{
 		edge:   edge,
 		up:     up,
 		depth:  depth,
-		status: idle,
+		status: int32(idle),
+		table:  tt,
+		count:  count,
 
 		state: state,
 		hand:  hand,
 
+		arity:  hand.Len(),
+		proven: hand.Len() == 0,
+
 		//down: make([]*Node, 0, 24), // average branching factor is 20.7
 
-		ε: ε,
-		c: c,
-		w: w,
+		conf: conf,
+		ε:    conf.Epsilon,
+	}
+
+	if node.proven { // terminal
+		node.exact = state.Score()
 	}
 
 	node.spinlock = newSpinlock()
@@ -202,10 +175,21 @@ func NewNode(up *Node, edge Move, state GameState, hand MoveSet, ε, c, w float6
 	return &node
 }
 
-// NewRoot initializes a node with an initial position and various constants used
-// during the search.
-func NewRoot(initial GameState, ε, c, w float64) *Node {
-	return NewNode(nil, nil, initial, initial.Moves(), ε, c, w)
+// NewRoot initializes a node with an initial position and the configuration used
+// during the search. A transposition table is attached to the tree when enabled
+// and supported by the game.
+func NewRoot(initial GameState, conf *Config) *Node {
+	root := NewNode(nil, nil, initial, initial.Moves(), conf)
+
+	root.count = &census{}
+	root.count.add(idle, 1)
+
+	if h, ok := initial.(Hasher); ok && conf.Transpositions {
+		root.table = newTable()
+		root.table.LoadOrStore(h.Hash(), root)
+	}
+
+	return root
 }
 
 // Best returns the best sequence found so far.
@@ -226,64 +210,105 @@ func (n *Node) Depth() int {
 	}
 }
 
-// Down returns a slice containing references to the children
+// Down returns a copy of the slice containing references to the children
 // of the calling node.
 func (n *Node) Down() []*Node {
-	return n.down
+	if n == nil {
+		return nil
+	}
+
+	n.Lock()
+	defer n.Unlock()
+	{
+		return append([]*Node(nil), n.down...)
+	}
 }
 
 // Downselect chooses next edge using linear ε-greedy algorithm:
 // It chooses a random node with probability ε and uses UCB with probability 1-ε
+// Random choices are drawn from rng.
 // see https://arxiv.org/pdf/1402.6028.pdf
-func (n *Node) Downselect() *Node {
-	p := 1.0
-	if v := n.Visits(); v > 0 {
-		p = n.ε // ε-greedy
-
-		// entropy logarithmic increase: p(0) = 0, p(1000)= 0.07, p(10000) = 0.21.
-		// tests are disappointing but the idea isn't dead.
-		// p = (1 - 1/math.Log(10+(v/200))) / 2
-	}
+func (n *Node) Downselect(rng *rand.Rand) *Node {
+	node, _ := n.downselect(rng)
+	return node
+}
 
-	var node *Node
+// downselect is Downselect, it also reports oversampling: all the children were
+// busy and a random one has been chosen anyway. Selection by value walks the
+// ranking of the children from the best one, solved children found on the way
+// are unranked.
+func (n *Node) downselect(rng *rand.Rand) (node *Node, oversampled bool) {
+	visited := n.Visits() > 0
+	loss := n.conf.VirtualLoss.Loss > 0
+	reset := false
 
 	n.Lock()
 	{
-		if rand.Float64() > p { // selection by value
-			by(value).sortDescending(n.down)
+		p := 1.0
+		if visited {
+			p = n.ε // ε-greedy
+
+			// entropy logarithmic increase: p(0) = 0, p(1000)= 0.07, p(10000) = 0.21.
+			// tests are disappointing but the idea isn't dead.
+			// p = (1 - 1/math.Log(10+(v/200))) / 2
+		}
+
+		// look for an idle node, solved nodes are not searched anymore.
+		var solved []*Node
+		searchable := func(child *Node) bool {
+			if child.IsSolved() {
+				solved = append(solved, child)
+				return false
+			}
+			return child.Status() == idle
+		}
+
+		if rng.Float64() > p { // selection by value
+			node = n.rank.first(searchable)
 		} else { // ε-greedy
 			swap := func(i, j int) { n.down[i], n.down[j] = n.down[j], n.down[i] }
-			rand.Shuffle(len(n.down), swap)
-		}
+			rng.Shuffle(len(n.down), swap)
 
-		// look for an idle node
-		for _, node = range n.down {
-			if node.GetLock() {
-				status := node.status
-				node.Unlock()
-				if status == idle {
-					// Resetting node's value is expected to exclude it from next selection.
-					// Eventually, the value will be set again by an updater.
-					n.value = math.Inf(-1)
-					goto undersampling
+			for _, child := range n.down {
+				if searchable(child) {
+					node = child
+					break
 				}
 			}
 		}
+
+		for _, child := range solved {
+			n.rank.remove(child)
+		}
+
+		if node != nil {
+			// Resetting node's value is expected to exclude it from next selection.
+			// Eventually, the value will be set again by an updater. Virtual
+			// loss makes it less likely to be selected instead.
+			reset = !loss
+			if reset {
+				n.value.store(math.Inf(-1))
+			}
+			goto undersampling
+		}
 		// oversampling:
 		// - feels like it could escape from local optimums here.
 		// - feels like a prover stage could be plugged-in here.
-		node = n.down[rand.Intn(len(n.down))]
+		node, oversampled = n.down[rng.Intn(len(n.down))], true
 		n.ε *= 2
-		log.Printf("downselect: oversampling %p over %d, increased entropy %g\n", node, len(n.down), n.ε)
 
 	undersampling:
 		// very core idea of Monte-Carlo tree: it's desirable to undersample, hopefully, with some sense.
 	}
 	n.Unlock()
 
+	if reset {
+		n.rerank()
+	}
+
 	//log.Printf("downselect: %p\n", node)
 
-	return node
+	return
 }
 
 // Edge returns the move which leads to the calling node.
@@ -299,31 +324,91 @@ func (n *Node) Edge() Move {
 	}
 }
 
-// Evaluate set the UCB value of the calling node.
-func (n *Node) Evaluate() float64 {
-	value := n.UCB()
+// edgeTo returns the move leading from the calling node to one of its children.
+func (n *Node) edgeTo(child *Node) Move {
+	child.Lock()
+	up, edge := child.up, child.edge
+	child.Unlock()
+
+	if up == n {
+		return edge
+	}
+
 	n.Lock()
+	defer n.Unlock()
 	{
-		n.value = value
+		return n.via[child]
 	}
-	n.Unlock()
+}
+
+// edgeToUnsafe is edgeTo without locking the calling node.
+func (n *Node) edgeToUnsafe(child *Node) Move {
+	if child.up == n {
+		return child.edge
+	}
+	return n.via[child]
+}
+
+// Evaluate set the UCB value of the calling node.
+func (n *Node) Evaluate() float64 {
+	value := n.UCB()
+	n.value.store(value)
+	n.devalue()
+	n.rerank()
 
 	return value
 }
 
-// ExpandOne creates and links a new children to the calling node.
+// ExpandOne creates and links a new children to the calling node. When the resulting
+// position is already in the transposition table, the existing node is linked instead.
 func (n *Node) ExpandOne(move Move) *Node {
 	state := n.State().Clone().Play(move)
-	moves := state.Moves()
 
-	node := NewNode(n, move, state, moves, n.ε, n.c, n.w)
+	var node *Node
+	if n.table != nil {
+		hash := state.(Hasher).Hash()
+
+		if node = n.table.Load(hash); node == nil {
+			node, _ = n.table.LoadOrStore(hash, NewNode(n, move, state, state.Moves(), n.conf))
+		}
+	} else {
+		node = NewNode(n, move, state, state.Moves(), n.conf)
+	}
+
+	transposed := node.Up() != n
+	if !transposed {
+		n.count.add(node.Status(), 1)
+	}
 
 	n.Lock()
 	{
 		n.down = append(n.down, node)
+		n.rank.add(node)
+		if transposed {
+			if n.via == nil {
+				n.via = make(map[*Node]Move)
+			}
+			n.via[node] = move
+		}
 	}
 	n.Unlock()
 
+	var solved bool
+
+	node.Lock()
+	{
+		if transposed {
+			node.links = append(node.links, n)
+		}
+		solved = node.proven
+	}
+	node.Unlock()
+
+	// The child is terminal or has been solved before being linked.
+	if solved {
+		n.solve()
+	}
+
 	//log.Printf("expand: %p\n", node)
 
 	return node
@@ -333,17 +418,27 @@ func (n *Node) ExpandOne(move Move) *Node {
 // the position of the calling node.
 func (n *Node) ExpandAll(value float64) {
 	for _, move := range n.Hand().List() {
-		node := n.ExpandOne(move)
-		node.SetValue(value)
+		if node := n.ExpandOne(move); node.Up() == n {
+			node.SetValue(value)
+		}
 	}
 
 	n.Lock()
 	{
-		n.hand = MoveSet{}
+		n.hand = noMoves
 	}
 	n.Unlock()
 }
 
+// Exact returns the best score reachable from the position of a solved node.
+func (n *Node) Exact() float64 {
+	n.Lock()
+	defer n.Unlock()
+	{
+		return n.exact
+	}
+}
+
 // Hand lists all the legal moves for the calling node.
 func (n *Node) Hand() MoveSet {
 	n.Lock()
@@ -380,23 +475,21 @@ func (n *Node) IsFringe() bool {
 	}
 }
 
-// IsSolved isn't used.
+// IsSolved is true when the exact value of the calling node is known: the node is
+// terminal or all of its children are solved.
 func (n *Node) IsSolved() bool {
+	if n == nil {
+		return false
+	}
+
 	n.Lock()
 	defer n.Unlock()
 	{
-		return 1 == n.solved/float64(n.hand.Len()+len(n.down))
+		return n.proven
 	}
 }
 
-// IsSolvedUnsafe isn't used.
-func (n *Node) IsSolvedUnsafe() bool {
-	return 1 == n.solved/float64(n.hand.Len()+len(n.down))
-
-}
-
-// IsTerminal is true if the calling node is the second to
-// last move of a game.
+// IsTerminal is true if the game is over in the position of the calling node.
 func (n *Node) IsTerminal() bool {
 	if n == nil {
 		return false
@@ -405,24 +498,20 @@ func (n *Node) IsTerminal() bool {
 	n.Lock()
 	defer n.Unlock()
 	{
-		return len(n.down) == 0 && n.hand.Len() == 1
+		return n.arity == 0
 	}
 }
 
 // Mean is the running mean score of the calling node.
 func (n *Node) Mean() float64 {
-	n.Lock()
-	defer n.Unlock()
-	{
-		return n.mean
-	}
+	return n.mean.load()
 }
 
-// RandomNewEdge removes and return a move from the calling node's hand.
-func (n *Node) RandomNewEdge() (move Move) {
+// RandomNewEdge removes and return a random move from the calling node's hand.
+func (n *Node) RandomNewEdge(rng *rand.Rand) (move Move) {
 	n.Lock()
 	{
-		move, n.hand = n.hand.Draw()
+		move, n.hand = n.hand.Draw(rng)
 	}
 	n.Unlock()
 
@@ -431,34 +520,32 @@ func (n *Node) RandomNewEdge() (move Move) {
 
 // SampleVariance is guaranteed to be numerically stable.
 func (n *Node) SampleVariance() float64 {
-	n.Lock()
-	defer n.Unlock()
-	{
-		return n.variance / (n.visits - 1)
-	}
+	s := n.statistics()
+	return s.variance / (s.visits - 1)
 }
 
 // SetStatus is a safe setter.
 func (n *Node) SetStatus(status NodeStatus) {
-	n.Lock()
-	{
-		n.status = status
+	if old := NodeStatus(atomic.SwapInt32(&n.status, int32(status))); old != status {
+		n.count.add(old, -1)
+		n.count.add(status, 1)
 	}
-	n.Unlock()
 }
 
 // SetValue is a safe setter.
 func (n *Node) SetValue(value float64) {
-	n.Lock()
-	{
-		n.value = value
-	}
-	n.Unlock()
+	n.value.store(value)
+	n.devalue()
+	n.rerank()
 }
 
 // State returns the position associated to the calling node.
 func (n *Node) State() GameState {
-	return n.state
+	n.Lock()
+	defer n.Unlock()
+	{
+		return n.state
+	}
 }
 
 // Status is a safe getter.
@@ -467,11 +554,7 @@ func (n *Node) Status() NodeStatus {
 		return null
 	}
 
-	n.Lock()
-	defer n.Unlock()
-	{
-		return n.status
-	}
+	return NodeStatus(atomic.LoadInt32(&n.status))
 }
 
 // StDev is a safe running standard deviation.
@@ -493,30 +576,20 @@ func (n *Node) String() string {
 			sb.WriteString("edge: nil\n")
 		}
 
-		if _, err := fmt.Fprintf(&sb, "depth: %d\n", n.depth); err != nil {
-			panic(err)
-		}
+		fmt.Fprintf(&sb, "depth: %d\n", n.depth)
 
-		sb.WriteString("status: " + n.status.String() + "\n")
+		sb.WriteString("status: " + NodeStatus(atomic.LoadInt32(&n.status)).String() + "\n")
 
-		if _, err := fmt.Fprintf(&sb, "solved : %g\n", n.solved); err != nil {
-			panic(err)
-		}
+		fmt.Fprintf(&sb, "solved : %g/%d, proven: %v, exact: %g\n", n.solved, n.arity, n.proven, n.exact)
 
 		sb.WriteByte('\n')
 
-		if _, err := fmt.Fprintf(&sb, "up: %p\n", n.up); err != nil {
-			panic(err)
-		}
+		fmt.Fprintf(&sb, "up: %p\n", n.up)
 
-		if _, err := fmt.Fprintf(&sb, "down: (%d)\n", len(n.down)); err != nil {
-			panic(err)
-		}
+		fmt.Fprintf(&sb, "down: (%d)\n", len(n.down))
 
 		for i := 0; i < len(n.down); i++ {
-			if _, err := fmt.Fprintf(&sb, "%2d: %v\t@%p\t", i, n.down[i].edge, n.down[i]); err != nil {
-				panic(err)
-			}
+			fmt.Fprintf(&sb, "%2d: %v\t@%p\t", i, n.edgeToUnsafe(n.down[i]), n.down[i])
 
 			if (i+1)%3 == 0 {
 				sb.WriteByte('\n')
@@ -525,9 +598,7 @@ func (n *Node) String() string {
 
 		sb.WriteByte('\n')
 
-		if _, err := fmt.Fprint(&sb, "\nhand: ", n.hand, "\n"); err != nil {
-			panic(err)
-		}
+		fmt.Fprint(&sb, "\nhand: ", n.hand, "\n")
 
 		sb.WriteString("\nstate:\n" + n.state.String() + "\n")
 
@@ -535,30 +606,30 @@ func (n *Node) String() string {
 
 		sb.WriteByte('\n')
 
-		if _, err := fmt.Fprintf(&sb, "value = %g\n", n.value); err != nil {
-			panic(err)
-		}
+		fmt.Fprintf(&sb, "value = %g\n", n.value.load())
 
-		if _, err := fmt.Fprintf(&sb, "mean = %g, visits = %g, variance = %g\n", n.mean, n.visits, n.variance); err != nil {
-			panic(err)
-		}
+		fmt.Fprintf(&sb, "mean = %g, visits = %g, variance = %g\n", n.mean.load(), n.visits.load(), n.variance.load())
 
-		if _, err := fmt.Fprintf(&sb, "ε = %g, c = %g, w = %g", n.ε, n.c, n.w); err != nil {
-			panic(err)
-		}
+		fmt.Fprintf(&sb, "worst = %g\n", n.worst.load())
+
+		fmt.Fprintf(&sb, "ε = %g, c = %g, w = %g", n.ε, n.conf.C, n.conf.W)
 	}
 	n.Unlock()
 	return sb.String()
 }
 
-// UCB calls the package wide enabled UCB formula.
+// UCB calls the formula of the search configuration.
 func (n *Node) UCB() float64 {
-	return SelectedUCB(n)
+	return n.conf.UCB(n)
 }
 
 // Up enables tree navigation toward tree's root.
 func (n *Node) Up() *Node {
-	return n.up
+	n.Lock()
+	defer n.Unlock()
+	{
+		return n.up
+	}
 }
 
 // UpdateTree guides the search: it back propagates outcomes from simulations
@@ -566,60 +637,161 @@ func (n *Node) Up() *Node {
 // running through this node that has been found so far. It maintains running
 // mean and variance with a numerically stable technique. Finally it computes
 // UCB values enabling next search iteration to select the most promising node.
+// In a DAG, the path of primary parents is followed.
 func (n *Node) UpdateTree(decision Decision) {
-	if n != nil {
-		n.Lock()
+	var path []*Node
+	for node := n; node != nil; node = node.Up() {
+		path = append(path, node)
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+
+	backup(path, decision)
+}
+
+// backup is UpdateTree along a path going from the root to the node the
+// decision has been simulated from. Transposed nodes share their statistics
+// between all their parents but only the ancestors on the path are updated.
+func backup(path []*Node, decision Decision) {
+	for i := len(path) - 1; i >= 0; i-- {
+		path[i].update(decision)
+	}
+
+	for _, node := range path {
+		node.Evaluate()
+	}
+}
+
+// update records an outcome in the calling node statistics.
+func (n *Node) update(decision Decision) {
+	n.Lock()
+	n.seq.begin()
+	{
+		visits := n.visits.load() + 1
+		n.visits.store(visits)
+
+		score := decision.score
+
+		// Running mean and variance from B. P. Welford.
+		// This variance computation is numerically stable.
+		// see:
+		// D.E. Knuth TAOCP Vol 2, page 232, 3rd edition.
+		old := n.mean.load()
+		cur := old + (score-old)/visits
+
+		n.variance.store(n.variance.load() + (score-old)*(score-cur))
+		n.mean.store(cur)
+
+		if score > n.best.Score() || visits == 1 {
+			n.best = decision
+			n.top.store(score)
+		}
+
+		if score < n.worst.load() || visits == 1 {
+			n.worst.store(score)
+		}
+	}
+	n.seq.end()
+	n.Unlock()
+}
+
+// solve records a newly solved child of the calling node. Once all of its children
+// are solved, the node is proven and its parents are notified in turn (MCTS-Solver).
+// Terminal nodes are proven at creation.
+// see https://dke.maastrichtuniversity.nl/m.winands/documents/uctloa.pdf
+func (n *Node) solve() {
+	var last bool
+
+	n.Lock()
+	{
+		n.solved++
+		last = n.solved == float64(n.arity)
+	}
+	n.Unlock()
+
+	if !last {
+		return
+	}
+
+	// The exact value is the best exact value of the children.
+	exact := math.Inf(-1)
+	for _, child := range n.Down() {
+		value := n.edgeTo(child).Score()
+
+		child.Lock()
 		{
-			/*
-				if decision.solved != 0 {
-					if n.solved = n.solved + decision.solved; !n.IsSolvedUnsafe() {
-						decision.solved = 0
-					}
-				}
-			*/
+			value += child.exact
+		}
+		child.Unlock()
+
+		if value > exact {
+			exact = value
+		}
+	}
+
+	// Publishing the proof and listing parents is atomic: parents linked
+	// later on are notified during expansion.
+	var parents []*Node
+
+	n.Lock()
+	{
+		n.exact, n.proven = exact, true
+		if n.up != nil && !n.pruned {
+			parents = append(parents, n.up)
+		}
+		parents = append(parents, n.links...)
+	}
+	n.Unlock()
 
-			n.visits++
+	for _, parent := range parents {
+		parent.solve()
+	}
+}
 
-			score := decision.score
+// Proof returns the optimal sequence from the position of a solved node, its
+// score is the exact value of the node. It's the best decision of a solved root.
+func (n *Node) Proof() Decision {
+	var proof Decision
 
-			// Running mean and variance from B. P. Welford.
-			// This variance computation is numerically stable.
-			// see:
-			// D.E. Knuth TAOCP Vol 2, page 232, 3rd edition.
-			old := n.mean
-			cur := old + (score-old)/n.visits
+	proof.solved = n.IsSolved()
 
-			n.variance = n.variance + (score-old)*(score-cur)
-			n.mean = cur
+	for node := n; node != nil; {
+		var next *Node
+		var move Move
 
-			if score > n.best.Score() {
-				n.best = decision
+		exact := math.Inf(-1)
+		for _, child := range node.Down() {
+			edge := node.edgeTo(child)
+			if value := edge.Score() + child.Exact(); child.IsSolved() && value > exact {
+				next, move, exact = child, edge, value
 			}
 		}
-		n.Unlock()
 
-		n.up.UpdateTree(decision)
+		if next == nil {
+			proof.score += node.State().Score()
+			break
+		}
 
-		n.Evaluate()
+		proof.moves = proof.moves.Enqueue(move)
+		proof.score += move.Score()
+
+		node = next
 	}
+
+	return proof
 }
 
 // value returns the calling node's search score.
 func (n *Node) Value() float64 {
-	n.Lock()
-	defer n.Unlock()
-	{
-		return n.value
-	}
+	return n.value.load()
 }
 
 // Variance maintains a running variance for the calling node.
 func (n *Node) Variance() float64 {
-	n.Lock()
-	defer n.Unlock()
-	{
-		return n.variance / n.visits
-	}
+	s := n.statistics()
+	return s.variance / s.visits
 }
 
 // Visits returns the number of simulations that run through the calling node.
@@ -628,9 +800,10 @@ func (n *Node) Visits() float64 {
 		return 1.0
 	}
 
-	n.Lock()
-	defer n.Unlock()
-	{
-		return n.visits
-	}
+	return n.visits.load()
+}
+
+// Worst returns the lowest score of the simulations that run through the calling node.
+func (n *Node) Worst() float64 {
+	return n.worst.load()
 }
@@ -8,18 +8,36 @@ package mcs
 
 import (
 	"math"
+	"reflect"
+	"runtime"
 )
 
-// SelectedUCB is the current in use formula. It isn't currently possible
-// to have more than one in use.
-var SelectedUCB = UCBTunedSinglePlayer
-
-// An UCB function is an effective implementation of a formula.
+// An UCB function is an effective implementation of a formula. The formula in use
+// is selected in the search configuration.
 type UCB func(*Node) float64
 
-// SelectUCB chooses the current active formula.
-func SelectUCB(fun UCB) {
-	SelectedUCB = fun
+// formulas lists the UCB functions known by name, see LoadTree.
+var formulas = []UCB{UCB1, UCBTunedSinglePlayer, UCBV, ADAUCB, KLUCB}
+
+// registered reports whether a formula is known by name.
+func registered(u UCB) bool {
+	if u == nil {
+		return false
+	}
+
+	for _, ucb := range formulas {
+		if ucb.String() == u.String() {
+			return true
+		}
+	}
+	return false
+}
+
+func (u UCB) String() string {
+	if u == nil {
+		return ""
+	}
+	return runtime.FuncForPC(reflect.ValueOf(u).Pointer()).Name()
 }
 
 // UCB1 is from [2002 Auer et Al]
@@ -27,14 +45,10 @@ func SelectUCB(fun UCB) {
 func UCB1(n *Node) float64 {
 	var np, ni, μι, C float64
 
-	np, ni = n.up.Visits(), n.Visits()
+	np, ni = n.Up().Visits(), n.Visits()
 
-	n.Lock()
-	{
-		μι = n.mean
-		C = n.c
-	}
-	n.Unlock()
+	μι = n.Mean()
+	C = n.conf.C
 
 	χi := math.Sqrt(math.Log(np) / ni)
 
@@ -48,17 +62,15 @@ func UCB1(n *Node) float64 {
 func UCBTunedSinglePlayer(n *Node) float64 {
 	var np, ni, βi, μι, σι, C, W float64
 
-	np, ni = n.up.Visits(), n.Visits()
+	s := n.statistics()
 
-	n.Lock()
-	{
-		βi = n.best.Score()
-		μι = n.mean
-		σι = n.variance
-		C = n.c
-		W = n.w // weighted best value
-	}
-	n.Unlock()
+	np, ni = n.Up().Visits(), s.visits
+
+	βi = s.best
+	μι = s.mean
+	σι = s.variance
+	C = n.conf.C
+	W = n.conf.W // weighted best value
 
 	// running average value
 
@@ -79,17 +91,15 @@ func UCBTunedSinglePlayer(n *Node) float64 {
 func UCBV(n *Node) float64 {
 	var np, ni, βi, μι, σι, C, W float64
 
-	np, ni = n.up.Visits(), n.Visits()
+	s := n.statistics()
 
-	n.Lock()
-	{
-		βi = n.best.Score()
-		μι = n.mean
-		σι = n.variance
-		C = n.c
-		W = n.w // weighted best value
-	}
-	n.Unlock()
+	np, ni = n.Up().Visits(), s.visits
+
+	βi = s.best
+	μι = s.mean
+	σι = s.variance
+	C = n.conf.C
+	W = n.conf.W // weighted best value
 
 	χi := C * math.Log(np) / ni
 
@@ -102,5 +112,87 @@ func UCBV(n *Node) float64 {
 	return value
 }
 
-// TODO: ADA-UCB [2018 Lattimore]
+// ADAUCB is from [2018 Lattimore]. The horizon is unknown during a search: the parent
+// visits count stands for it. Rewards are scaled to [0,1], hence they are 1/2-subgaussian.
 // see http://www.jmlr.org/papers/volume19/17-513/17-513.pdf
+func ADAUCB(n *Node) float64 {
+	var np, ni, μι, Hi float64
+
+	np, ni = n.Up().Visits(), n.Visits()
+
+	μι = scaled(n)
+
+	for _, sibling := range n.Up().Down() {
+		nj := sibling.Visits()
+		Hi += math.Min(nj, math.Sqrt(ni*nj))
+	}
+	Hi *= ni
+
+	χi := math.Log(np / Hi) // log+
+	if χi < 0 {
+		χi = 0
+	}
+
+	value := μι + math.Sqrt(χi/(2*ni))
+
+	return value
+}
+
+// KLUCB is from [2011 Garivier, Cappé] with Bernoulli divergence. Rewards are scaled to [0,1].
+// The upper bound is found by bisection.
+// see https://arxiv.org/pdf/1102.2490.pdf
+func KLUCB(n *Node) float64 {
+	var np, ni, μι float64
+
+	np, ni = n.Up().Visits(), n.Visits()
+
+	μι = scaled(n)
+
+	χi := math.Log(np) / ni
+
+	lo, hi := μι, 1.0
+	for i := 0; i < 16; i++ {
+		q := (lo + hi) / 2
+		if klBernoulli(μι, q) > χi {
+			hi = q
+		} else {
+			lo = q
+		}
+	}
+
+	value := lo
+
+	return value
+}
+
+// klBernoulli is the Kullback-Leibler divergence between Bernoulli distributions.
+func klBernoulli(p, q float64) float64 {
+	const ε = 1e-15
+
+	p = math.Min(math.Max(p, ε), 1-ε)
+	q = math.Min(math.Max(q, ε), 1-ε)
+
+	return p*math.Log(p/q) + (1-p)*math.Log((1-p)/(1-q))
+}
+
+// scaled returns the running mean of the calling node scaled to [0,1] by the range of
+// scores observed through its parent: siblings share the same scale.
+func scaled(n *Node) float64 {
+	var lo, hi, μι float64
+
+	up := n.Up()
+	if up == nil {
+		up = n
+	}
+
+	s := up.statistics()
+	lo, hi = s.worst, s.best
+
+	μι = n.Mean()
+
+	if hi <= lo {
+		return 1
+	}
+
+	return math.Min(math.Max((μι-lo)/(hi-lo), 0), 1)
+}
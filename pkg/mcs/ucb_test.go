@@ -1,15 +1,63 @@
 package mcs
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
-func TestSelectUCB(t *testing.T) {
+func TestUCB1(t *testing.T) {
 
 }
 
-func TestUCB1(t *testing.T) {
+func TestUCBTunedSinglePlayer(t *testing.T) {
 
 }
 
-func TestUCBTunedSinglePlayer(t *testing.T) {
+// newBandit returns the children of a root whose outcomes are scaled by k.
+func newBandit(k float64) []*Node {
+	root := GrowTree(NewRoot(newToy(1, 2, 3), NewConfig(0.03, 40, 0)))
+
+	outcomes := [][]float64{
+		{10, 12, 11, 10, 12, 11, 10, 12}, // well known, good
+		{9, 10},                          // barely known, average
+		{2, 3, 1, 2, 3},                  // known, bad
+	}
+
+	children := root.Down()
+	for i, scores := range outcomes {
+		for _, score := range scores {
+			children[i].UpdateTree(Decision{score: k * score})
+		}
+	}
+
+	return children
+}
+
+func testScaledUCB(t *testing.T, ucb UCB) {
+	children, scaled := newBandit(1), newBandit(40)
+
+	for i := range children {
+		v, w := ucb(children[i]), ucb(scaled[i])
+		if math.Abs(v-w) > 1e-9 {
+			t.Errorf("child %d: value depends on score scale: %g != %g", i, v, w)
+		}
+	}
+
+	if ucb(children[1]) <= ucb(children[2]) {
+		t.Errorf("a barely known average child should be preferred over a known bad one")
+	}
+}
+
+func TestADAUCB(t *testing.T) {
+	testScaledUCB(t, ADAUCB)
+}
+
+func TestKLUCB(t *testing.T) {
+	testScaledUCB(t, KLUCB)
 
+	for _, child := range newBandit(1) {
+		if v, μ := KLUCB(child), scaled(child); v < μ || v > 1 {
+			t.Errorf("kl-ucb: %g is not in [%g, 1]", v, μ)
+		}
+	}
 }
@@ -7,75 +7,104 @@
 package mcs
 
 import (
+	"context"
 	"time"
 )
 
 // ConfidentSearch implements a classical UCT as specified in [2006 Kocsis, Szepesvári]
 // see http://ggp.stanford.edu/readings/uct.pdf
-func ConfidentSearch(root *Node, policies []GamePolicy, duration time.Duration) Decision {
-
-	if root == nil {
-		// TODO: error handling
-		panic("no tree")
+// Playouts cut off by the deadline are discarded: they lack their end of game.
+func ConfidentSearch(ctx context.Context, root *Node, policies []GamePolicy) (Decision, Stats, error) {
+	if decision, err := check(root, policies); err != nil {
+		return decision, Stats{}, err
 	}
 
 	tree := GrowTree(root)
 
-	done := make(chan struct{})
-	timeout := make(chan bool)
+	ctx, track := newTracker(ctx, tree)
+	defer track.stop()
 
-	go func() {
-		time.Sleep(duration)
-		close(timeout)
-	}()
+	done := ctx.Done()
+	rng := newRand(tree.conf.Seed)
 
 	for {
 		select {
 
-		case <-timeout:
-			close(done)
-			return tree.Best()
+		case <-done:
+			return conclude(tree, decide(tree), rng, policies[0], track), track.stats(), nil
 
 		default:
-			if root.IsSolved() {
-				close(done)
-				return tree.Best()
+			if tree.IsSolved() {
+				return decide(tree), track.stats(), nil
 			}
-
-			const VisitThreshold = 8
+			track.trim()
 
 			var score float64
 			var moves MoveSequence
 
 			node := tree
+			path := []*Node{tree}
 
+			start := time.Now()
 			for node.IsExpanded() {
-				node = node.Downselect()
+				next, oversampled := node.downselect(rng)
+				if oversampled {
+					track.oversampled()
+				}
 
-				move := node.Edge()
+				move := node.edgeTo(next)
 				moves = moves.Enqueue(move)
 
 				score += move.Score()
+
+				node = next
+				path = append(path, node)
 			}
 
-			if !node.IsTerminal() && node.Visits() > VisitThreshold {
-				node = node.ExpandOne(node.RandomNewEdge())
+			if !node.IsTerminal() && node.Visits() > node.conf.VisitThreshold && track.reserve() {
+				move := node.RandomNewEdge(rng)
+				parent := node
+				if node = node.ExpandOne(move); node.Up() == parent {
+					track.created()
+				} else {
+					track.release() // transposition
+				}
 
-				move := node.Edge()
 				moves = moves.Enqueue(move)
 
 				score += move.Score()
+
+				path = append(path, node)
 			}
+			track.reached(len(path) - 1)
+			track.spent(walking, start)
 
+			// The search has been stopped while walking, eg. the expansion has
+			// used up the nodes budget: a playout would be cut off.
+			if ctx.Err() != nil {
+				continue
+			}
+
+			start = time.Now()
 			clone := node.State().Clone()
-			sampled := clone.Sample(done, policies[0])
+			sampled := simulate(ctx, rng, clone, policies[0])
+			track.spent(sampling, start)
+
+			if ctx.Err() != nil { // cut off before the end of the game
+				continue
+			}
 
 			sampled.moves = moves.Join(sampled.moves)
 			sampled.score += score
 
-			node.UpdateTree(sampled)
+			if track.playout() {
+				start = time.Now()
+				backup(path, sampled)
+				track.spent(updating, start)
+				track.offer(sampled)
+			}
 		}
 	}
 }
 
-//func NestedConfidentSearch(root *Node, policies []GamePolicy, duration time.Duration) Decision {}
+//func NestedConfidentSearch(ctx context.Context, root *Node, policies []GamePolicy) (Decision, Stats, error) {}
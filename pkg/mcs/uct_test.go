@@ -1,7 +1,77 @@
 package mcs
 
-import "testing"
+import (
+	"context"
+	"testing"
+	"time"
+)
 
 func TestUCT(t *testing.T) {
+	game := newToy(4, 1, 3, 2)
 
+	root := NewRoot(game.Clone(), NewConfig(0.03, 40, 0))
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	result, _, _ := ConfidentSearch(ctx, root, []GamePolicy{nil})
+	cancel()
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("uct: small game not solved early (%v)", elapsed)
+	}
+
+	if !result.Solved() || !root.IsSolved() {
+		t.Fatalf("uct: small game not solved")
+	}
+
+	if best := game.optimum(); result.Score() != best {
+		t.Errorf("uct: expected proven optimum %g, got %g", best, result.Score())
+	}
+
+	score, err := replay(game, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if score != result.Score() {
+		t.Errorf("uct: replayed %g, expected %g", score, result.Score())
+	}
+}
+
+func TestUCT_terminal(t *testing.T) {
+	root := NewRoot(newToy(), NewConfig(0.03, 40, 0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	result, _, _ := ConfidentSearch(ctx, root, []GamePolicy{nil})
+	cancel()
+
+	if !result.Solved() || result.Moves().Len() != 0 {
+		t.Errorf("uct: terminal root not solved")
+	}
+}
+
+func TestUCT_cutoff(t *testing.T) {
+	items := make([]int, 200)
+	for i := range items {
+		items[i] = i
+	}
+	game := newToy(items...)
+
+	// Deadlines cut playouts off: the decision is complete anyway.
+	for i, timeout := range []time.Duration{0, 10 * time.Microsecond, 100 * time.Microsecond, time.Millisecond, 5 * time.Millisecond} {
+		conf := NewConfig(0.03, 40, 0)
+		conf.Seed = int64(i)
+		root := NewRoot(game.Clone(), conf)
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		result, _, _ := ConfidentSearch(ctx, root, []GamePolicy{nil})
+		cancel()
+
+		score, err := replay(game, result)
+		if err != nil {
+			t.Fatalf("uct: %v: %v", timeout, err)
+		}
+		if score != result.Score() {
+			t.Errorf("uct: %v: replayed %g, expected %g", timeout, score, result.Score())
+		}
+	}
 }